@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// 生成的配置对象命名方式
+	externalizeNaming string
+	// 生成的配置清单文件
+	externalizeFile string
+	// 敏感名称模式
+	externalizeSecretPatterns []string
+)
+
+// externalizeCmd 表示externalize命令
+var externalizeCmd = &cobra.Command{
+	Use:   "externalize",
+	Short: "将env中的字面值移至生成的ConfigMap和Secret",
+	Long: `查找工作负载中env[].value的字面值，名称匹配敏感模式（PASSWORD、TOKEN、KEY等）、值符合已知的令牌格式
+（私钥、JWT、URL中的密码等）或像随机密钥的变量放入Secret，与scan命令的判断一致，其余放入ConfigMap；
+生成的清单写入输入目录下的 externalized-config.yaml，并将字面值替换为configMapKeyRef/secretKeyRef引用。
+配置对象的命名与解析策略一致：exact 为环境变量名的小写形式，workload 为 <工作负载>-config / <工作负载>-secret。
+已有同名对象中存在相同的值时直接引用；值不同或缺少该键时保留字面值并给出警告。
+生成的Secret使用明文stringData，提交前请加密（例如使用sealed-secrets或SOPS）。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		options := newProcessOptions()
+		options.ExternalizeNaming = externalizeNaming
+		options.ExternalizeFile = externalizeFile
+		options.SecretPatterns = externalizeSecretPatterns
+
+		switch options.ExternalizeNaming {
+		case utils.ResolverExact, utils.ResolverWorkload:
+		default:
+			fmt.Println("选项无效: 命名方式必须是 exact 或 workload")
+			os.Exit(1)
+		}
+
+		run(options, (*processor.MainProcessor).Externalize)
+	},
+	Example: `  # 输出差异，确认哪些值会被移至Secret
+  k8sconfig-processor externalize -m dry-run
+
+  # 按工作负载命名并直接改写清单
+  k8sconfig-processor externalize --naming workload -m overwrite --force
+
+  # 只将名称包含DSN或PASSWORD的变量视为敏感信息（指定的模式替换默认模式）
+  k8sconfig-processor externalize --secret-pattern DSN --secret-pattern PASSWORD`,
+}
+
+func init() {
+	externalizeCmd.Flags().StringVar(&externalizeNaming, "naming", utils.ResolverExact, "生成的配置对象命名方式: exact（环境变量名）, workload（<工作负载>-config / <工作负载>-secret）")
+	externalizeCmd.Flags().StringVar(&externalizeFile, "file", "", "生成的配置清单文件，相对于输入目录且不能位于其之外（默认 "+utils.ExternalizedFile+"）")
+	externalizeCmd.Flags().StringArrayVar(&externalizeSecretPatterns, "secret-pattern", nil, "视为敏感信息的环境变量名模式，指定后替换默认模式（PASSWORD, PASSWD, SECRET, TOKEN, KEY, CREDENTIAL, PRIVATE）；可重复指定")
+
+	// 添加externalize命令到根命令
+	rootCmd.AddCommand(externalizeCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd 表示lint命令
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "检查已有的配置引用",
+	Long: `检查工作负载中已有的ConfigMap/Secret引用是否有效，包括env的valueFrom、envFrom、
+configMap/secret卷和projected卷，报告不存在的对象、缺失的键和跨命名空间引用。
+无效引用报告为错误，optional引用缺失报告为警告，退出码由--fail-on决定。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// lint只读取文件，不需要输出目录和覆盖确认
+		options := newProcessOptions()
+		options.Mode = utils.ModeSafe
+
+		run(options, (*processor.MainProcessor).Lint)
+	},
+	Example: `  # 检查当前目录
+  k8sconfig-processor lint
+
+  # 检查指定目录
+  k8sconfig-processor lint -i ./manifests
+
+  # 输出JSON报告，optional引用缺失也视为失败
+  k8sconfig-processor lint --report-format json --report-file lint.json --fail-on warning
+
+  # 检查kustomize的输出
+  kustomize build overlays/prod | k8sconfig-processor lint -f -`,
+}
+
+func init() {
+	// 添加lint命令到根命令
+	rootCmd.AddCommand(lintCmd)
+}
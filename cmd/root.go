@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/k8sconfig-processor/pkg/processor"
 	"github.com/k8sconfig-processor/pkg/utils"
@@ -14,12 +16,59 @@ var (
 	inputDir string
 	// 输出目录
 	outputDir string
+	// 流模式的输入文件
+	filenames []string
+	// 配置来源
+	configSources []string
+	// 集群来源
+	fromCluster       bool
+	kubeconfig        string
+	kubeContext       string
+	clusterNamespaces []string
+	secretKeysOnly    bool
 	// 处理模式
 	mode string
+	// 包含和排除的glob模式
+	includes []string
+	excludes []string
 	// 是否强制覆盖
 	force bool
 	// 是否执行预检查
 	precheck bool
+	// 检查模式
+	check bool
+	// 失败条件
+	failOn string
+	// 名称解析策略
+	resolvers []string
+	// 前缀规则
+	prefixRules []string
+	// 自定义工作负载配置文件
+	workloadConfig string
+	// 是否自动查找容器列表
+	discoverContainers bool
+	// 环境变量引用风格
+	envStyle string
+	// 是否写入配置哈希注解
+	checksumAnnotations bool
+	// 是否生成占位的配置对象
+	createMissing bool
+	// 区分占位ConfigMap和Secret的敏感名称模式
+	secretPatterns []string
+	// 差异格式
+	diffFormat string
+	// 差异上下文行数
+	diffContext int
+	// 差异是否着色
+	color bool
+	// 无法解析文档的处理策略
+	onParseError string
+	// 并发处理的文件数
+	jobs int
+	// 报告格式
+	reportFormat string
+	// 报告输出文件
+	reportFile string
 )
 
 // rootCmd 表示没有调用子命令时的基础命令
@@ -28,50 +77,184 @@ var rootCmd = &cobra.Command{
 	Short: "Kubernetes配置处理工具",
 	Long: `Kubernetes配置处理工具，用于自动化处理K8s配置文件中的环境变量。
 从ConfigMap和Secret中自动查找匹配的配置来填充未设置值的环境变量。`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		// 创建处理选项
-		options := &utils.ProcessOptions{
-			InputDir:  inputDir,
-			OutputDir: outputDir,
-			Mode:      mode,
-			Force:     force,
-			Precheck:  precheck,
-		}
+		run(newProcessOptions(), (*processor.MainProcessor).Execute)
+	},
+}
 
-		// 验证选项
-		if err := validateOptions(options); err != nil {
-			fmt.Println("选项无效:", err)
-			os.Exit(1)
-		}
+// 验证选项、创建处理器并执行action，按报告内容和失败条件设置退出码；所有命令共用
+func run(options *utils.ProcessOptions, action func(*processor.MainProcessor) error) {
+	// 验证选项
+	if err := validateOptions(options); err != nil {
+		fmt.Println("选项无效:", err)
+		os.Exit(1)
+	}
+
+	// 创建处理器
+	mainProcessor, err := processor.NewMainProcessor(options)
+	if err != nil {
+		fmt.Println("选项无效:", err)
+		os.Exit(1)
+	}
 
-		// 创建处理器
-		mainProcessor := processor.NewMainProcessor(options)
+	// 流模式：标准输出只包含处理后的YAML，提示信息和报告写入标准错误
+	if len(options.Files) > 0 {
+		mainProcessor.Log = os.Stderr
+	}
 
-		// 执行处理
-		if err := mainProcessor.Execute(); err != nil {
-			fmt.Println("执行失败:", err)
-			os.Exit(1)
-		}
-	},
+	// 执行处理
+	if err := action(mainProcessor); err != nil {
+		fmt.Fprintln(mainProcessor.Log, "执行失败:", err)
+		os.Exit(utils.ExitFailure)
+	}
+
+	// 按报告内容和失败条件设置退出码
+	if code := mainProcessor.ExitCode(); code != utils.ExitClean {
+		os.Exit(code)
+	}
+}
+
+// 根据命令行标志创建处理选项
+func newProcessOptions() *utils.ProcessOptions {
+	return &utils.ProcessOptions{
+		InputDir:  inputDir,
+		Files:     filenames,
+		OutputDir: outputDir,
+		Mode:      mode,
+		Include:   includes,
+		Exclude:   excludes,
+		Force:     force,
+
+		FromCluster:       fromCluster,
+		Kubeconfig:        kubeconfig,
+		KubeContext:       kubeContext,
+		ClusterNamespaces: clusterNamespaces,
+		SecretKeysOnly:    secretKeysOnly,
+
+		Precheck:  precheck,
+		Check:     check,
+		FailOn:    failOn,
+		Resolvers: resolvers,
+
+		WorkloadConfig:     workloadConfig,
+		DiscoverContainers: discoverContainers,
+		EnvStyle:           envStyle,
+
+		ChecksumAnnotations: checksumAnnotations,
+		CreateMissing:       createMissing,
+		SecretPatterns:      secretPatterns,
+
+		DiffFormat:  diffFormat,
+		DiffContext: diffContext,
+		Color:       color,
+
+		OnParseError: onParseError,
+		Jobs:         jobs,
+		ReportFormat: reportFormat,
+		ReportFile:   reportFile,
+	}
 }
 
 // 验证处理选项
 func validateOptions(options *utils.ProcessOptions) error {
-	// 验证输入目录
-	if options.InputDir == "" {
-		return fmt.Errorf("输入目录不能为空")
+	if len(options.Files) > 0 {
+		// 流模式：验证输入文件存在，结果写入标准输出，不需要输入目录
+		for _, file := range options.Files {
+			if file == utils.StdinFile {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				return fmt.Errorf("输入文件不存在: %s", file)
+			}
+		}
+	} else {
+		// 验证输入目录
+		if options.InputDir == "" {
+			return fmt.Errorf("输入目录不能为空")
+		}
+
+		// 验证输入目录存在
+		if _, err := os.Stat(options.InputDir); os.IsNotExist(err) {
+			return fmt.Errorf("输入目录不存在: %s", options.InputDir)
+		}
 	}
 
-	// 验证输入目录存在
-	if _, err := os.Stat(options.InputDir); os.IsNotExist(err) {
-		return fmt.Errorf("输入目录不存在: %s", options.InputDir)
+	// 验证处理模式，未知的模式会被当作覆盖模式写入输入文件
+	switch options.Mode {
+	case utils.ModeSafe, utils.ModeOverwrite, utils.ModeDryRun:
+	default:
+		return fmt.Errorf("处理模式必须是 safe, overwrite 或 dry-run")
 	}
 
-	// 如果是覆盖模式，检查是否设置了force标志
-	if options.Mode == utils.ModeOverwrite && !options.Force {
+	// 如果是覆盖模式，检查是否设置了force标志；检查模式和流模式不写入文件
+	if options.Mode == utils.ModeOverwrite && !options.Force && !options.Check && len(options.Files) == 0 {
 		return fmt.Errorf("覆盖模式需要设置--force标志")
 	}
 
+	// 验证环境变量引用风格
+	switch options.EnvStyle {
+	case utils.EnvStyleKeyRef, utils.EnvStyleEnvFrom, utils.EnvStyleAuto:
+	default:
+		return fmt.Errorf("环境变量引用风格必须是 keyRef, envFrom 或 auto")
+	}
+
+	// 验证差异格式
+	switch options.DiffFormat {
+	case utils.DiffFormatUnified, utils.DiffFormatSideBySide, utils.DiffFormatJSONPatch:
+	default:
+		return fmt.Errorf("差异格式必须是 unified, side-by-side 或 json-patch")
+	}
+	if options.DiffContext < 0 {
+		return fmt.Errorf("差异上下文行数不能为负数")
+	}
+
+	// 验证无法解析文档的处理策略
+	switch options.OnParseError {
+	case utils.ParseErrorKeep, utils.ParseErrorSkip:
+	default:
+		return fmt.Errorf("无法解析文档的处理策略必须是 keep 或 skip")
+	}
+
+	if options.Jobs < 0 {
+		return fmt.Errorf("并发数不能为负数")
+	}
+
+	// 解析配置来源，格式为 PATH 或 PATH=PRIORITY
+	sources, err := parseConfigSources(configSources)
+	if err != nil {
+		return err
+	}
+	options.ConfigSources = sources
+
+	// 验证失败条件
+	switch options.FailOn {
+	case utils.FailOnChange, utils.FailOnWarning, utils.FailOnError:
+	default:
+		return fmt.Errorf("失败条件必须是 change, warning 或 error")
+	}
+
+	// 验证报告格式
+	switch options.ReportFormat {
+	case utils.ReportFormatText, utils.ReportFormatJSON, utils.ReportFormatSARIF, utils.ReportFormatJUnit:
+	default:
+		return fmt.Errorf("报告格式必须是 text, json, sarif 或 junit")
+	}
+	// 标准输出和标准错误中还有差异、处理后的YAML和提示信息，机器可读的报告必须单独写入文件
+	if options.ReportFormat != utils.ReportFormatText && options.ReportFile == "" {
+		return fmt.Errorf("报告格式 %s 需要同时指定--report-file", options.ReportFormat)
+	}
+
+	// 解析前缀规则，格式为 PREFIX=name
+	options.PrefixRules = make(map[string]string)
+	for _, rule := range prefixRules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("前缀规则格式无效: %s (应为 PREFIX=name)", rule)
+		}
+		options.PrefixRules[parts[0]] = parts[1]
+	}
+
 	// 设置默认值
 	if options.OutputDir == "" && options.Mode == utils.ModeSafe {
 		options.OutputDir = utils.DefaultOutputDir
@@ -80,6 +263,29 @@ func validateOptions(options *utils.ProcessOptions) error {
 	return nil
 }
 
+// 解析配置来源参数，未指定优先级时使用默认优先级
+func parseConfigSources(values []string) ([]utils.ConfigSource, error) {
+	var sources []utils.ConfigSource
+	for _, value := range values {
+		source := utils.ConfigSource{Path: value, Priority: utils.DefaultSourcePriority}
+
+		if index := strings.LastIndex(value, "="); index >= 0 {
+			priority, err := strconv.Atoi(value[index+1:])
+			if err != nil {
+				return nil, fmt.Errorf("配置来源格式无效: %s (应为 PATH 或 PATH=PRIORITY)", value)
+			}
+			source.Path = value[:index]
+			source.Priority = priority
+		}
+
+		if _, err := os.Stat(source.Path); err != nil {
+			return nil, fmt.Errorf("配置来源不存在: %s", source.Path)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
 // Execute 添加所有子命令到根命令并设置标志
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -92,7 +298,33 @@ func init() {
 	// 初始化标志
 	rootCmd.PersistentFlags().StringVarP(&inputDir, "input", "i", ".", "输入目录，包含YAML文件")
 	rootCmd.PersistentFlags().StringVarP(&outputDir, "output", "o", utils.DefaultOutputDir, "输出目录")
+	rootCmd.PersistentFlags().StringArrayVarP(&filenames, "filename", "f", nil, "流模式：读取该文件（- 表示标准输入），处理后的YAML写入标准输出，报告写入标准错误；可重复指定")
 	rootCmd.PersistentFlags().StringVarP(&mode, "mode", "m", utils.ModeSafe, "处理模式: safe（安全）, overwrite（覆盖）, dry-run（演示）")
-	rootCmd.PersistentFlags().BoolVarP(&force, "force", "f", false, "强制覆盖模式，谨慎使用")
+	rootCmd.PersistentFlags().StringArrayVar(&configSources, "config-source", nil, "只用于查找ConfigMap和Secret的目录或文件，不会被改写；格式为 PATH 或 PATH=PRIORITY，可重复指定（输入目录的优先级为100）")
+	rootCmd.PersistentFlags().BoolVar(&fromCluster, "from-cluster", false, "从集群读取ConfigMap和Secret（只需要list权限），与清单中的配置合并，清单中的同名对象优先")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "kubeconfig路径，默认使用KUBECONFIG环境变量或~/.kube/config")
+	rootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "kubeconfig中的上下文，默认使用当前上下文")
+	rootCmd.PersistentFlags().StringSliceVar(&clusterNamespaces, "namespace", nil, "从集群读取的命名空间，可指定多个，默认使用上下文的命名空间")
+	rootCmd.PersistentFlags().BoolVar(&secretKeysOnly, "secret-keys-only", false, "只读取集群中Secret的键，不保留值")
+	rootCmd.PersistentFlags().StringArrayVar(&includes, "include", nil, "只处理匹配该glob模式的文件，可重复指定（例如 apps/**/*.yaml）")
+	rootCmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "跳过匹配该glob模式的文件和目录，可重复指定（例如 **/charts）")
+	rootCmd.PersistentFlags().BoolVar(&force, "force", false, "强制覆盖模式，谨慎使用")
 	rootCmd.PersistentFlags().BoolVarP(&precheck, "precheck", "p", false, "执行预检查")
+	rootCmd.PersistentFlags().BoolVar(&check, "check", false, "检查模式：验证文件是否已处理完毕，不写入任何文件，有变更时以非零状态退出")
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", utils.FailOnError, "以非零状态退出的条件: change, warning, error")
+	rootCmd.PersistentFlags().StringSliceVar(&resolvers, "resolvers", utils.DefaultResolvers, "名称解析策略及优先级: exact, workload, prefix, key")
+	rootCmd.PersistentFlags().StringArrayVar(&prefixRules, "prefix-rule", nil, "前缀规则，格式为 PREFIX=name，可重复指定（例如 DB_=db-config）")
+	rootCmd.PersistentFlags().StringVar(&workloadConfig, "workload-config", "", "自定义工作负载配置文件，定义CRD的pod规格路径")
+	rootCmd.PersistentFlags().StringVar(&envStyle, "env-style", utils.EnvStyleKeyRef, "环境变量引用风格: keyRef（逐个valueFrom）, envFrom（合并为envFrom）, auto（变量较多时合并）")
+	rootCmd.PersistentFlags().BoolVar(&checksumAnnotations, "checksum-annotations", false, "在pod模板上写入checksum/<名称>注解，值为引用的ConfigMap/Secret数据的哈希，配置变化时触发滚动更新")
+	rootCmd.PersistentFlags().BoolVar(&createMissing, "create-missing", false, "为未找到配置的环境变量生成值为TODO的占位ConfigMap/Secret（按--secret-pattern的名称模式区分），写入输入目录下的 "+utils.StubsFile+" 并引用它们")
+	rootCmd.Flags().StringArrayVar(&secretPatterns, "secret-pattern", nil, "--create-missing视为敏感信息、放入Secret的环境变量名模式，指定后替换默认模式（PASSWORD, PASSWD, SECRET, TOKEN, KEY, CREDENTIAL, PRIVATE）；可重复指定")
+	rootCmd.PersistentFlags().StringVar(&diffFormat, "diff-format", utils.DiffFormatUnified, "干运行模式的差异格式: unified, side-by-side, json-patch")
+	rootCmd.PersistentFlags().IntVar(&diffContext, "diff-context", utils.DefaultDiffContext, "差异的上下文行数")
+	rootCmd.PersistentFlags().BoolVar(&color, "color", false, "差异输出着色")
+	rootCmd.PersistentFlags().StringVar(&onParseError, "on-parse-error", utils.ParseErrorKeep, "文件中有无法解析的文档时: keep（原样保留该文档）, skip（跳过整个文件）")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "并发处理的文件数，默认为CPU核数")
+	rootCmd.PersistentFlags().StringVar(&reportFormat, "report-format", utils.ReportFormatText, "报告格式: text, json, sarif, junit；json、sarif和junit需要指定--report-file")
+	rootCmd.PersistentFlags().StringVar(&reportFile, "report-file", "", "报告输出文件，未指定时文本报告与提示信息一起输出")
+	rootCmd.PersistentFlags().BoolVar(&discoverContainers, "discover-containers", false, "在未知资源中自动查找包含name和image的containers列表")
 }
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 创建通过验证的默认选项
+func testOptions(t *testing.T) *utils.ProcessOptions {
+	t.Helper()
+	return &utils.ProcessOptions{
+		InputDir:     t.TempDir(),
+		Mode:         utils.ModeSafe,
+		EnvStyle:     utils.EnvStyleKeyRef,
+		DiffFormat:   utils.DiffFormatUnified,
+		OnParseError: utils.ParseErrorKeep,
+		FailOn:       utils.FailOnError,
+		ReportFormat: utils.ReportFormatText,
+	}
+}
+
+func TestValidateMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		force   bool
+		check   bool
+		wantErr bool
+	}{
+		{"安全模式", utils.ModeSafe, false, false, false},
+		{"干运行", utils.ModeDryRun, false, false, false},
+		{"覆盖模式需要force", utils.ModeOverwrite, false, false, true},
+		{"覆盖模式", utils.ModeOverwrite, true, false, false},
+		{"检查模式不写入文件", utils.ModeOverwrite, false, true, false},
+		// 拼写错误的模式不能绕过--force
+		{"拼写错误", "overwirte", false, false, true},
+		{"拼写错误且设置force", "overwirte", true, false, true},
+		{"空模式", "", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions(t)
+			options.Mode = tt.mode
+			options.Force = tt.force
+			options.Check = tt.check
+			if err := validateOptions(options); (err != nil) != tt.wantErr {
+				t.Errorf("validateOptions(mode=%q, force=%v) = %v, 期望错误: %v", tt.mode, tt.force, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 机器可读的报告不能与差异和处理后的YAML混在标准输出中
+func TestValidateReportFile(t *testing.T) {
+	tests := []struct {
+		format  string
+		file    string
+		wantErr bool
+	}{
+		{utils.ReportFormatText, "", false},
+		{utils.ReportFormatJSON, "", true},
+		{utils.ReportFormatSARIF, "", true},
+		{utils.ReportFormatJUnit, "", true},
+		{utils.ReportFormatJSON, "report.json", false},
+		{utils.ReportFormatSARIF, "report.sarif", false},
+		{utils.ReportFormatJUnit, "report.xml", false},
+	}
+
+	for _, tt := range tests {
+		options := testOptions(t)
+		options.ReportFormat = tt.format
+		options.ReportFile = tt.file
+		if err := validateOptions(options); (err != nil) != tt.wantErr {
+			t.Errorf("validateOptions(report-format=%s, report-file=%q) = %v, 期望错误: %v", tt.format, tt.file, err, tt.wantErr)
+		}
+	}
+}
+
+// -f是--filename的简写，"-f -"从标准输入读取并开启流模式
+func TestFlagShorthands(t *testing.T) {
+	t.Cleanup(func() {
+		filenames = nil
+		rootCmd.PersistentFlags().Lookup("filename").Changed = false
+	})
+
+	if err := rootCmd.ParseFlags([]string{"-f", "-"}); err != nil {
+		t.Fatalf("解析 -f - 失败: %v", err)
+	}
+	options := newProcessOptions()
+	if len(options.Files) != 1 || options.Files[0] != "-" {
+		t.Errorf("-f - 应开启流模式并读取标准输入, 实际 Files=%q", options.Files)
+	}
+	if options.Force {
+		t.Error("-f 不应设置 --force")
+	}
+	if flag := rootCmd.PersistentFlags().Lookup("force"); flag == nil || flag.Shorthand != "" {
+		t.Errorf("--force 不应有简写, 实际 %+v", flag)
+	}
+
+	// 多余的位置参数应报错，而不是处理当前目录
+	if err := rootCmd.Args(rootCmd, []string{"-"}); err == nil {
+		t.Error("根命令应拒绝位置参数")
+	}
+}
+
+// 根命令的--secret-pattern传给占位配置对象的分类，scan和externalize各自绑定自己的变量
+func TestSecretPatternFlags(t *testing.T) {
+	t.Cleanup(func() {
+		secretPatterns, scanSecretPatterns, externalizeSecretPatterns = nil, nil, nil
+	})
+
+	if err := rootCmd.Flags().Set("secret-pattern", "LICENSE"); err != nil {
+		t.Fatalf("根命令缺少--secret-pattern: %v", err)
+	}
+	if options := newProcessOptions(); len(options.SecretPatterns) != 1 || options.SecretPatterns[0] != "LICENSE" {
+		t.Errorf("SecretPatterns = %q, 期望 [LICENSE]", options.SecretPatterns)
+	}
+
+	if err := scanCmd.Flags().Set("secret-pattern", "DSN"); err != nil {
+		t.Fatal(err)
+	}
+	if len(externalizeSecretPatterns) != 0 || len(secretPatterns) != 1 {
+		t.Errorf("scan的--secret-pattern不应影响其他命令: externalize=%q, root=%q", externalizeSecretPatterns, secretPatterns)
+	}
+}
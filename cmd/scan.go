@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// 敏感名称模式
+var scanSecretPatterns []string
+
+// scanCmd 表示scan命令
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "扫描清单中硬编码的密钥",
+	Long: `检查工作负载中env的字面值、ConfigMap的data以及所有注解，报告像密钥的内容：
+已知的令牌格式（JWT、AWS访问密钥、私钥、GitHub/Slack令牌、URL中的密码等）、
+名称匹配敏感模式（PASSWORD、TOKEN、KEY等）的字面值，以及熵较高的随机字符串。
+已知格式和名称匹配报告为错误，只有熵较高的报告为警告；ConfigMap中名称敏感的键单独报告为
+secret-in-configmap。报告中的值已遮盖，不修改任何文件。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// scan只读取文件，不需要输出目录和覆盖确认
+		options := newProcessOptions()
+		options.Mode = utils.ModeSafe
+		options.SecretPatterns = scanSecretPatterns
+
+		run(options, (*processor.MainProcessor).Scan)
+	},
+	Example: `  # 扫描当前目录
+  k8sconfig-processor scan
+
+  # 在CI中输出SARIF报告，高熵字符串也视为失败
+  k8sconfig-processor scan -i ./manifests --report-format sarif --report-file scan.sarif --fail-on warning
+
+  # 扫描kustomize的输出
+  kustomize build overlays/prod | k8sconfig-processor scan -f -`,
+}
+
+func init() {
+	scanCmd.Flags().StringArrayVar(&scanSecretPatterns, "secret-pattern", nil, "视为敏感信息的名称模式，指定后替换默认模式（PASSWORD, PASSWD, SECRET, TOKEN, KEY, CREDENTIAL, PRIVATE）；可重复指定")
+
+	// 添加scan命令到根命令
+	rootCmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/spf13/cobra"
+)
+
+// versionCmd 表示version命令
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "为ConfigMap和Secret生成带内容哈希的不可变版本",
+	Long: `将每个ConfigMap/Secret清单重命名为 <名称>-<内容哈希> 并设置immutable: true（与kustomize的名称后缀哈希相同），
+然后将所有工作负载中的configMapKeyRef、secretKeyRef、envFrom和卷引用改写为新名称。
+配置内容变化时名称随之变化，运行中的pod不会读到新配置，回滚时旧版本的配置仍然存在。
+原始名称记录在 k8sconfig-processor/base-name 注解中，内容不变时重复执行不会产生变更。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		run(newProcessOptions(), (*processor.MainProcessor).Version)
+	},
+	Example: `  # 输出差异，确认重命名和引用改写
+  k8sconfig-processor version -m dry-run
+
+  # 直接改写清单
+  k8sconfig-processor version -m overwrite --force
+
+  # 处理kustomize的输出
+  kustomize build overlays/prod | k8sconfig-processor version -f - | kubectl apply -f -`,
+}
+
+func init() {
+	// 添加version命令到根命令
+	rootCmd.AddCommand(versionCmd)
+}
@@ -0,0 +1,125 @@
+package cluster
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// 每次列出对象的数量
+const pageSize = 500
+
+// 集群来源选项
+type Options struct {
+	// kubeconfig路径，为空时使用默认的加载规则（KUBECONFIG环境变量或~/.kube/config）
+	Kubeconfig string
+	// kubeconfig中的上下文，为空时使用当前上下文
+	Context string
+	// 读取的命名空间，为空时使用上下文的命名空间
+	Namespaces []string
+	// 只读取Secret的键，不读取值
+	SecretKeysOnly bool
+}
+
+// 根据kubeconfig创建客户端，返回客户端和上下文的默认命名空间
+func NewClient(options Options) (kubernetes.Interface, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if options.Kubeconfig != "" {
+		rules.ExplicitPath = options.Kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: options.Context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("加载kubeconfig失败: %v", err)
+	}
+
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("读取上下文的命名空间失败: %v", err)
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建集群客户端失败: %v", err)
+	}
+
+	return client, namespace, nil
+}
+
+// 列出命名空间中的ConfigMap和Secret，只使用list权限，
+// Secret的值直接写入stringData，keysOnly时只保留键
+func LoadResources(ctx context.Context, client kubernetes.Interface, namespaces []string, keysOnly bool) ([]utils.KubeResource, error) {
+	var resources []utils.KubeResource
+
+	for _, namespace := range namespaces {
+		continueToken := ""
+		for {
+			list, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{Limit: pageSize, Continue: continueToken})
+			if err != nil {
+				return nil, fmt.Errorf("列出命名空间 %s 中的ConfigMap失败: %v", namespace, err)
+			}
+
+			for _, configMap := range list.Items {
+				resource := newResource(utils.ConfigMapKind, configMap.Namespace, configMap.Name)
+				resource.Data = configMap.Data
+				resource.BinaryData = make(map[string]string)
+				for key, value := range configMap.BinaryData {
+					resource.BinaryData[key] = base64.StdEncoding.EncodeToString(value)
+				}
+				resources = append(resources, resource)
+			}
+
+			continueToken = list.Continue
+			if continueToken == "" {
+				break
+			}
+		}
+
+		continueToken = ""
+		for {
+			list, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{Limit: pageSize, Continue: continueToken})
+			if err != nil {
+				return nil, fmt.Errorf("列出命名空间 %s 中的Secret失败: %v", namespace, err)
+			}
+
+			for _, secret := range list.Items {
+				resource := newResource(utils.SecretKind, secret.Namespace, secret.Name)
+				resource.Type = string(secret.Type)
+				resource.KeysOnly = keysOnly
+				resource.StringData = make(map[string]string)
+				for key, value := range secret.Data {
+					if keysOnly {
+						resource.StringData[key] = ""
+					} else {
+						resource.StringData[key] = string(value)
+					}
+				}
+				resources = append(resources, resource)
+			}
+
+			continueToken = list.Continue
+			if continueToken == "" {
+				break
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// 创建资源的基本字段
+func newResource(kind, namespace, name string) utils.KubeResource {
+	var resource utils.KubeResource
+	resource.APIVersion = "v1"
+	resource.Kind = kind
+	resource.Metadata.Namespace = namespace
+	resource.Metadata.Name = name
+	return resource
+}
@@ -0,0 +1,199 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func configMap(namespace, name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Data:       data,
+	}
+}
+
+func secret(namespace, name string, data map[string]string) *corev1.Secret {
+	result := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       make(map[string][]byte),
+	}
+	for key, value := range data {
+		result.Data[key] = []byte(value)
+	}
+	return result
+}
+
+// 资源的 类型/命名空间/名称，排序后用于比较
+func resourceIDs(resources []utils.KubeResource) []string {
+	var ids []string
+	for _, resource := range resources {
+		ids = append(ids, resource.Kind+"/"+resource.Metadata.Namespace+"/"+resource.Metadata.Name)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func findResource(resources []utils.KubeResource, kind, namespace, name string) *utils.KubeResource {
+	for i := range resources {
+		if resources[i].Kind == kind && resources[i].Metadata.Namespace == namespace && resources[i].Metadata.Name == name {
+			return &resources[i]
+		}
+	}
+	return nil
+}
+
+func TestLoadResourcesNamespaces(t *testing.T) {
+	client := fake.NewClientset(
+		configMap("default", "app-config", map[string]string{"LOG_LEVEL": "info"}),
+		configMap("prod", "app-config", map[string]string{"LOG_LEVEL": "warn"}),
+		configMap("other", "ignored", map[string]string{"A": "1"}),
+		secret("prod", "app-secret", map[string]string{"PASSWORD": "s3cret"}),
+		secret("other", "ignored", map[string]string{"B": "2"}),
+	)
+
+	tests := []struct {
+		name       string
+		namespaces []string
+		want       []string
+	}{
+		{"单个命名空间", []string{"default"}, []string{"ConfigMap/default/app-config"}},
+		{"多个命名空间", []string{"default", "prod"}, []string{
+			"ConfigMap/default/app-config", "ConfigMap/prod/app-config", "Secret/prod/app-secret",
+		}},
+		{"不存在的命名空间", []string{"missing"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := LoadResources(context.Background(), client, tt.namespaces, false)
+			if err != nil {
+				t.Fatalf("LoadResources: %v", err)
+			}
+			if got := resourceIDs(resources); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("读取的资源 = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+
+	resources, err := LoadResources(context.Background(), client, []string{"default", "prod"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm := findResource(resources, utils.ConfigMapKind, "prod", "app-config"); cm == nil || cm.Data["LOG_LEVEL"] != "warn" {
+		t.Errorf("prod中的ConfigMap不正确: %+v", cm)
+	}
+	s := findResource(resources, utils.SecretKind, "prod", "app-secret")
+	if s == nil || s.StringData["PASSWORD"] != "s3cret" || s.Type != string(corev1.SecretTypeOpaque) || s.KeysOnly {
+		t.Errorf("Secret的值应解码后写入stringData: %+v", s)
+	}
+}
+
+func TestLoadResourcesKeysOnly(t *testing.T) {
+	client := fake.NewClientset(
+		configMap("default", "app-config", map[string]string{"LOG_LEVEL": "info"}),
+		secret("default", "app-secret", map[string]string{"PASSWORD": "s3cret", "TOKEN": "abc"}),
+	)
+
+	resources, err := LoadResources(context.Background(), client, []string{"default"}, true)
+	if err != nil {
+		t.Fatalf("LoadResources: %v", err)
+	}
+
+	s := findResource(resources, utils.SecretKind, "default", "app-secret")
+	if s == nil {
+		t.Fatal("缺少app-secret")
+	}
+	if !s.KeysOnly {
+		t.Error("只读取键的Secret应标记为KeysOnly")
+	}
+	want := map[string]string{"PASSWORD": "", "TOKEN": ""}
+	if !reflect.DeepEqual(s.StringData, want) {
+		t.Errorf("只读取键时StringData = %v, 期望 %v", s.StringData, want)
+	}
+
+	// ConfigMap的值不受影响
+	if cm := findResource(resources, utils.ConfigMapKind, "default", "app-config"); cm == nil || cm.Data["LOG_LEVEL"] != "info" {
+		t.Errorf("ConfigMap的值不应被清空: %+v", cm)
+	}
+}
+
+// fake clientset不支持分页，使用reactor按continue令牌分页返回
+func TestLoadResourcesPagination(t *testing.T) {
+	const total, page = 1200, pageSize
+	var items []corev1.ConfigMap
+	for i := 0; i < total; i++ {
+		items = append(items, *configMap("default", fmt.Sprintf("config-%04d", i), map[string]string{"INDEX": strconv.Itoa(i)}))
+	}
+
+	client := fake.NewClientset()
+	var requests []metav1.ListOptions
+	client.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		options := action.(k8stesting.ListActionImpl).ListOptions
+		requests = append(requests, options)
+
+		start := 0
+		if options.Continue != "" {
+			var err error
+			if start, err = strconv.Atoi(options.Continue); err != nil {
+				return true, nil, fmt.Errorf("无效的continue令牌: %s", options.Continue)
+			}
+		}
+		end := start + int(options.Limit)
+		list := &corev1.ConfigMapList{}
+		if end < total {
+			list.Continue = strconv.Itoa(end)
+		} else {
+			end = total
+		}
+		list.Items = items[start:end]
+		return true, list, nil
+	})
+
+	resources, err := LoadResources(context.Background(), client, []string{"default"}, false)
+	if err != nil {
+		t.Fatalf("LoadResources: %v", err)
+	}
+
+	if len(resources) != total {
+		t.Fatalf("读取了%d个ConfigMap, 期望%d个", len(resources), total)
+	}
+	for i, resource := range resources {
+		if resource.Data["INDEX"] != strconv.Itoa(i) {
+			t.Fatalf("第%d个ConfigMap不正确: %+v", i, resource)
+		}
+	}
+
+	wantTokens := []string{"", strconv.Itoa(page), strconv.Itoa(2 * page)}
+	if len(requests) != len(wantTokens) {
+		t.Fatalf("发出了%d次列表请求, 期望%d次", len(requests), len(wantTokens))
+	}
+	for i, request := range requests {
+		if request.Limit != pageSize || request.Continue != wantTokens[i] {
+			t.Errorf("第%d次请求 limit=%d continue=%q, 期望 limit=%d continue=%q",
+				i, request.Limit, request.Continue, pageSize, wantTokens[i])
+		}
+	}
+}
+
+func TestLoadResourcesError(t *testing.T) {
+	client := fake.NewClientset()
+	client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	if _, err := LoadResources(context.Background(), client, []string{"default"}, false); err == nil {
+		t.Error("没有list权限时应返回错误")
+	}
+}
@@ -0,0 +1,333 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANSI颜色
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+	colorBold  = "\033[1m"
+)
+
+// 行级编辑操作类型
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// 行级编辑操作
+type lineOp struct {
+	kind opKind
+	// 在原文件中的行下标（从0开始），插入操作为-1
+	aIndex int
+	// 在新文件中的行下标（从0开始），删除操作为-1
+	bIndex int
+	// 行内容，包含换行符
+	text string
+}
+
+// 差异块
+type hunk struct {
+	ops    []lineOp
+	aStart int
+	aCount int
+	bStart int
+	bCount int
+}
+
+// 生成git风格的统一差异，内容相同时返回空字符串
+func Unified(oldName, newName string, a, b []byte, context int, color bool) string {
+	ops := lineOps(splitLines(string(a)), splitLines(string(b)))
+	hunks := buildHunks(ops, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	paint := painter(color)
+
+	out.WriteString(paint(colorBold, fmt.Sprintf("--- %s", oldName)) + "\n")
+	out.WriteString(paint(colorBold, fmt.Sprintf("+++ %s", newName)) + "\n")
+
+	for _, h := range hunks {
+		out.WriteString(paint(colorCyan, fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.aStart, h.aCount), hunkRange(h.bStart, h.bCount))) + "\n")
+		for _, op := range h.ops {
+			switch op.kind {
+			case opEqual:
+				writeLine(&out, " ", op.text, "", paint)
+			case opDelete:
+				writeLine(&out, "-", op.text, colorRed, paint)
+			case opInsert:
+				writeLine(&out, "+", op.text, colorGreen, paint)
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// 生成左右对照的差异，每栏宽度为width个字符
+func SideBySide(oldName, newName string, a, b []byte, context int, width int, color bool) string {
+	ops := lineOps(splitLines(string(a)), splitLines(string(b)))
+	hunks := buildHunks(ops, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	paint := painter(color)
+
+	out.WriteString(paint(colorBold, pad(oldName, width)+" | "+newName) + "\n")
+
+	for _, h := range hunks {
+		out.WriteString(paint(colorCyan, fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.aStart, h.aCount), hunkRange(h.bStart, h.bCount))) + "\n")
+
+		for i := 0; i < len(h.ops); {
+			if h.ops[i].kind == opEqual {
+				text := trimNewline(h.ops[i].text)
+				out.WriteString(pad(text, width) + "   " + truncate(text, width) + "\n")
+				i++
+				continue
+			}
+
+			// 将相邻的删除和插入配对显示
+			var deleted, inserted []string
+			for i < len(h.ops) && h.ops[i].kind == opDelete {
+				deleted = append(deleted, trimNewline(h.ops[i].text))
+				i++
+			}
+			for i < len(h.ops) && h.ops[i].kind == opInsert {
+				inserted = append(inserted, trimNewline(h.ops[i].text))
+				i++
+			}
+
+			for row := 0; row < len(deleted) || row < len(inserted); row++ {
+				switch {
+				case row < len(deleted) && row < len(inserted):
+					out.WriteString(paint(colorRed, pad(deleted[row], width)) + " | " + paint(colorGreen, truncate(inserted[row], width)) + "\n")
+				case row < len(deleted):
+					out.WriteString(paint(colorRed, pad(deleted[row], width)) + " <\n")
+				default:
+					out.WriteString(pad("", width) + " > " + paint(colorGreen, truncate(inserted[row], width)) + "\n")
+				}
+			}
+		}
+	}
+
+	return out.String()
+}
+
+// 按行拆分，每行保留换行符
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// 使用Myers算法计算最短编辑脚本
+func lineOps(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	trace = append(trace, append([]int(nil), v...))
+
+	// 回溯得到编辑操作
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 2; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: opEqual, aIndex: x, bIndex: y, text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, lineOp{kind: opInsert, aIndex: -1, bIndex: y, text: b[y]})
+			} else {
+				x--
+				ops = append(ops, lineOp{kind: opDelete, aIndex: x, bIndex: -1, text: a[x]})
+			}
+		}
+	}
+
+	// 反转为正序
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// 将编辑操作按上下文行数分组为差异块
+func buildHunks(ops []lineOp, context int) []hunk {
+	if context < 0 {
+		context = 0
+	}
+
+	var hunks []hunk
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		// 找到变更块的范围，间隔不超过2倍上下文的变更合并为一块
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, newHunk(ops, start, end))
+		i = end
+	}
+
+	return hunks
+}
+
+// 根据操作范围创建差异块
+func newHunk(ops []lineOp, start, end int) hunk {
+	h := hunk{ops: ops[start:end]}
+
+	// 计算起始行号：取范围之前最近的行号
+	aLine, bLine := 0, 0
+	for _, op := range ops[:start] {
+		if op.kind != opInsert {
+			aLine++
+		}
+		if op.kind != opDelete {
+			bLine++
+		}
+	}
+
+	for _, op := range h.ops {
+		if op.kind != opInsert {
+			h.aCount++
+		}
+		if op.kind != opDelete {
+			h.bCount++
+		}
+	}
+
+	h.aStart = aLine + 1
+	h.bStart = bLine + 1
+	if h.aCount == 0 {
+		h.aStart = aLine
+	}
+	if h.bCount == 0 {
+		h.bStart = bLine
+	}
+	return h
+}
+
+// 格式化差异块的行范围
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// 输出一行差异，缺少结尾换行时添加提示
+func writeLine(out *strings.Builder, prefix, text, color string, paint func(string, string) string) {
+	out.WriteString(paint(color, prefix+trimNewline(text)) + "\n")
+	if !strings.HasSuffix(text, "\n") {
+		out.WriteString("\\ No newline at end of file\n")
+	}
+}
+
+// 返回着色函数，未启用颜色时原样返回
+func painter(enabled bool) func(color, text string) string {
+	return func(color, text string) string {
+		if !enabled || color == "" {
+			return text
+		}
+		return color + text + colorReset
+	}
+}
+
+func trimNewline(text string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+}
+
+// 截断到指定宽度
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
+
+// 截断并填充到指定宽度
+func pad(text string, width int) string {
+	text = truncate(text, width)
+	return text + strings.Repeat(" ", width-len([]rune(text)))
+}
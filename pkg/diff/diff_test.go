@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 生成 1..n 的行，changes中的行号替换为指定内容
+func numberedLines(n int, changes map[int]string) string {
+	var out strings.Builder
+	for i := 1; i <= n; i++ {
+		if text, ok := changes[i]; ok {
+			out.WriteString(text + "\n")
+			continue
+		}
+		out.WriteString(strconv.Itoa(i) + "\n")
+	}
+	return out.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "内容相同",
+			a:    "a\nb\n", b: "a\nb\n", context: 3,
+			want: "",
+		},
+		{
+			name: "间隔不超过两倍上下文的变更合并为一块",
+			a:    numberedLines(9, nil), b: numberedLines(9, map[int]string{3: "x", 6: "y"}), context: 1,
+			want: "--- a\n+++ b\n" +
+				"@@ -2,6 +2,6 @@\n 2\n-3\n+x\n 4\n 5\n-6\n+y\n 7\n",
+		},
+		{
+			name: "间隔超过两倍上下文的变更分为两块",
+			a:    numberedLines(9, nil), b: numberedLines(9, map[int]string{3: "x", 7: "z"}), context: 1,
+			want: "--- a\n+++ b\n" +
+				"@@ -2,3 +2,3 @@\n 2\n-3\n+x\n 4\n" +
+				"@@ -6,3 +6,3 @@\n 6\n-7\n+z\n 8\n",
+		},
+		{
+			name: "上下文为0",
+			a:    numberedLines(5, nil), b: numberedLines(5, map[int]string{3: "x"}), context: 0,
+			want: "--- a\n+++ b\n@@ -3 +3 @@\n-3\n+x\n",
+		},
+		{
+			name: "文件开头和结尾的上下文不越界",
+			a:    "a\nb\n", b: "x\nb\ny\n", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,3 @@\n-a\n+x\n b\n+y\n",
+		},
+		{
+			name: "新建文件",
+			a:    "", b: "x\ny\n", context: 3,
+			want: "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "清空文件",
+			a:    "x\n", b: "", context: 3,
+			want: "--- a\n+++ b\n@@ -1 +0,0 @@\n-x\n",
+		},
+		{
+			name: "两边都缺少末尾换行",
+			a:    "a\nb", b: "a\nc", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n",
+		},
+		{
+			name: "只添加末尾换行",
+			a:    "a\nb", b: "a\nb\n", context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "末尾没有换行的行作为上下文",
+			a:    "a\nb\nc", b: "x\nb\nc", context: 3,
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n-a\n+x\n b\n c\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("a", "b", []byte(tt.a), []byte(tt.b), tt.context, false)
+			if got != tt.want {
+				t.Errorf("Unified() =\n%s\n期望:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedColor(t *testing.T) {
+	got := Unified("a", "b", []byte("a\n"), []byte("b\n"), 3, true)
+	for _, want := range []string{colorBold + "--- a" + colorReset, colorCyan + "@@ -1 +1 @@" + colorReset,
+		colorRed + "-a" + colorReset, colorGreen + "+b" + colorReset} {
+		if !strings.Contains(got, want) {
+			t.Errorf("着色输出中缺少 %q:\n%q", want, got)
+		}
+	}
+}
+
+// 编辑操作中的相等和删除行组成原文件，相等和插入行组成新文件
+func TestLineOps(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"a\n", ""},
+		{"", "a\n"},
+		{"a\nb\nc\n", "a\nc\n"},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n"},
+		{"x\ny\nz\n", "1\n2\n3\n"},
+		{"same\nsame\nsame\n", "same\nnew\nsame\nsame\n"},
+	}
+
+	for _, tt := range tests {
+		ops := lineOps(splitLines(tt.a), splitLines(tt.b))
+		var a, b strings.Builder
+		changes := 0
+		for _, op := range ops {
+			if op.kind != opInsert {
+				a.WriteString(op.text)
+			}
+			if op.kind != opDelete {
+				b.WriteString(op.text)
+			}
+			if op.kind != opEqual {
+				changes++
+			}
+		}
+		if a.String() != tt.a || b.String() != tt.b {
+			t.Errorf("lineOps(%q, %q) 无法还原两个文件: %q, %q", tt.a, tt.b, a.String(), b.String())
+		}
+		if tt.a == tt.b && changes != 0 {
+			t.Errorf("lineOps(%q, %q) 相同内容不应有变更", tt.a, tt.b)
+		}
+	}
+
+	// 最短编辑脚本：经典示例的编辑距离为5
+	ops := lineOps(splitLines("a\nb\nc\na\nb\nb\na\n"), splitLines("c\nb\na\nb\na\nc\n"))
+	changes := 0
+	for _, op := range ops {
+		if op.kind != opEqual {
+			changes++
+		}
+	}
+	if changes != 5 {
+		t.Errorf("编辑操作数 = %d, 期望 5", changes)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	got := SideBySide("old", "new", []byte("a\nb\nc\n"), []byte("a\nB\nc\nd\n"), 1, 6, false)
+	want := "old    | new\n" +
+		"@@ -1,3 +1,4 @@\n" +
+		"a        a\n" +
+		"b      | B\n" +
+		"c        c\n" +
+		"       > d\n"
+	if got != want {
+		t.Errorf("SideBySide() =\n%q\n期望:\n%q", got, want)
+	}
+
+	if got := SideBySide("old", "new", []byte("a\n"), []byte("a\n"), 3, 10, false); got != "" {
+		t.Errorf("内容相同时应返回空字符串, 实际 %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package k8sconfig
+
+import (
+	"fmt"
+
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 读取配置来源失败
+type SourceError = processor.SourceError
+
+// 选项无效
+type OptionError struct {
+	// 选项名称
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("选项 %s 无效: %v", e.Option, e.Err)
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// 生成输出失败，例如输出校验发现文档丢失；此时不会返回部分处理的内容
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("生成输出失败: %v", e.Err)
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// 报告中存在达到失败条件的发现
+type FindingsError struct {
+	// 失败条件: change, warning, error
+	FailOn string
+	// 达到失败条件的发现
+	Findings []Finding
+}
+
+func (e *FindingsError) Error() string {
+	if len(e.Findings) == 1 {
+		return e.Findings[0].String()
+	}
+	return fmt.Sprintf("%d 个发现达到失败条件 %s，第一个: %s", len(e.Findings), e.FailOn, e.Findings[0].String())
+}
+
+// 筛选达到失败条件的发现
+func newFindingsError(failOn string, findings []Finding) *FindingsError {
+	levels := map[string]int{utils.SeverityInfo: 1, utils.SeverityWarning: 2, utils.SeverityError: 3}
+	thresholds := map[string]int{utils.FailOnChange: 1, utils.FailOnWarning: 2, utils.FailOnError: 3}
+
+	err := &FindingsError{FailOn: failOn}
+	for _, finding := range findings {
+		if levels[finding.Severity] >= thresholds[failOn] {
+			err.Findings = append(err.Findings, finding)
+		}
+	}
+	return err
+}
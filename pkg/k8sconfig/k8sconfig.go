@@ -0,0 +1,132 @@
+// k8sconfig 是环境变量处理的库接口：在内存中处理多文档YAML，
+// 不读写输入输出文件，也不向标准输出打印任何内容
+package k8sconfig
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 未指定名称时输入在报告中使用的名称
+const DefaultInputName = "<input>"
+
+// 报告中的单条发现
+type Finding = utils.Finding
+
+// 配置来源、名称解析策略及其请求和结果，与命令行使用的实现相同
+type (
+	Source         = processor.Source
+	Resolver       = processor.Resolver
+	ResolveRequest = processor.ResolveRequest
+	Resolution     = processor.Resolution
+)
+
+// 处理报告
+type Report struct {
+	// 输入是否被修改
+	Modified bool
+	// 成功更新的资源数
+	SuccessfulUpdates int
+	// 所有变更、警告和错误，按行号排序
+	Findings []Finding
+}
+
+// 返回指定级别的发现
+func (r Report) Filter(severity string) []Finding {
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if finding.Severity == severity {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// 处理器，配置完成后可以并发使用；每次处理都重新读取配置来源
+type Processor struct {
+	// 输入在报告中使用的名称
+	name string
+	// 处理选项，与命令行选项相同
+	options utils.ProcessOptions
+	// 配置来源
+	sources []processor.PrioritySource
+	// 自定义解析策略，为空时使用options中的策略名称
+	resolvers []Resolver
+	// 失败条件，为空时不因报告内容返回错误
+	failOn string
+}
+
+// 创建处理器
+func New(opts ...Option) (*Processor, error) {
+	p := &Processor{
+		name: DefaultInputName,
+		options: utils.ProcessOptions{
+			Resolvers:    utils.DefaultResolvers,
+			EnvStyle:     utils.EnvStyleKeyRef,
+			OnParseError: utils.ParseErrorKeep,
+		},
+	}
+
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+
+	// 提前创建一次，校验解析策略和工作负载配置
+	if _, err := processor.NewMainProcessor(&p.options); err != nil {
+		return nil, &OptionError{Option: "resolvers/workload-config", Err: err}
+	}
+
+	return p, nil
+}
+
+// 使用给定的选项处理多文档YAML
+func Process(ctx context.Context, input []byte, opts ...Option) ([]byte, Report, error) {
+	p, err := New(opts...)
+	if err != nil {
+		return nil, Report{}, err
+	}
+	return p.Process(ctx, input)
+}
+
+// 处理多文档YAML，返回处理后的内容和报告；未修改的文档与输入逐字节相同。
+// 错误为*OptionError、*SourceError、*OutputError、*FindingsError或ctx的错误；
+// 返回*FindingsError时处理后的内容仍然有效
+func (p *Processor) Process(ctx context.Context, input []byte) ([]byte, Report, error) {
+	options := p.options
+	mainProcessor, err := processor.NewMainProcessor(&options)
+	if err != nil {
+		return nil, Report{}, &OptionError{Option: "resolvers/workload-config", Err: err}
+	}
+	mainProcessor.Output = io.Discard
+	mainProcessor.Log = io.Discard
+	mainProcessor.Sources = p.sources
+	if len(p.resolvers) > 0 {
+		mainProcessor.WorkloadProcessor.Resolver.Resolvers = p.resolvers
+	}
+
+	output, err := mainProcessor.ProcessContent(ctx, p.name, input)
+	report := Report{
+		Modified:          mainProcessor.Report.ProcessedFiles > 0,
+		SuccessfulUpdates: mainProcessor.Report.SuccessfulUpdates,
+		Findings:          mainProcessor.Report.Findings,
+	}
+	if err != nil {
+		var sourceErr *SourceError
+		if errors.As(err, &sourceErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, report, err
+		}
+		return nil, report, &OutputError{Err: err}
+	}
+
+	if p.failOn != "" && mainProcessor.Report.ExitCode(p.failOn) != utils.ExitClean {
+		return output, report, newFindingsError(p.failOn, report.Findings)
+	}
+
+	return output, report, nil
+}
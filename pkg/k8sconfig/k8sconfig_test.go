@@ -0,0 +1,311 @@
+package k8sconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const libraryConfigMap = `# 应用配置
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL:   info     # 对齐的注释
+  PORT: "8080"
+`
+
+const libraryService = `---
+apiVersion: v1
+kind: Service
+metadata: {name: web}   # 流式映射
+spec:
+  ports:
+  - port: 80
+`
+
+const libraryDeployment = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`
+
+// 捕获fn执行期间写入标准输出和标准错误的内容
+func captureStdio(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	}()
+
+	captured := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		captured <- string(data)
+	}()
+
+	fn()
+	writer.Close()
+	return <-captured
+}
+
+// 未修改的文档逐字节保留，处理过程不向标准输出或标准错误打印任何内容
+func TestProcessKeepsUnchangedDocuments(t *testing.T) {
+	input := libraryConfigMap + libraryService + libraryDeployment
+
+	var output []byte
+	var report Report
+	var err error
+	printed := captureStdio(t, func() {
+		output, report, err = Process(context.Background(), []byte(input))
+	})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if printed != "" {
+		t.Errorf("库接口不应打印任何内容, 实际输出:\n%s", printed)
+	}
+
+	if !report.Modified || report.SuccessfulUpdates != 1 {
+		t.Errorf("报告 = %+v, 期望修改了一个资源", report)
+	}
+	if !bytes.HasPrefix(output, []byte(libraryConfigMap+libraryService)) {
+		t.Errorf("未修改的文档应逐字节保留:\n%s", output)
+	}
+	if !bytes.Contains(output, []byte("configMapKeyRef")) {
+		t.Errorf("工作负载应引用app-config:\n%s", output)
+	}
+
+	// 没有需要修改的内容时输出与输入完全相同
+	unchanged := libraryConfigMap + libraryService
+	output, report, err = Process(context.Background(), []byte(unchanged))
+	if err != nil || report.Modified || string(output) != unchanged {
+		t.Errorf("Process(未修改的输入) = %q, %+v, %v", output, report, err)
+	}
+}
+
+// 在settings中按小写的键查找的解析策略
+type lowercaseResolver struct{}
+
+func (lowercaseResolver) Name() string {
+	return "lowercase"
+}
+
+func (lowercaseResolver) Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution {
+	key := strings.ToLower(request.EnvName)
+	value, exists := cache.ConfigMaps[request.Namespace]["settings"][key]
+	if !exists {
+		return nil
+	}
+	return []Resolution{{Value: value, ConfigName: "settings", ConfigKind: utils.ConfigMapKind, Key: key, Strategy: "lowercase"}}
+}
+
+func TestWithResolvers(t *testing.T) {
+	settings := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  log_level: debug\n"
+
+	output, report, err := Process(context.Background(), []byte(libraryDeployment),
+		WithSource(YAMLSource("settings.yaml", []byte(settings)), 0),
+		WithResolvers(lowercaseResolver{}))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if !bytes.Contains(output, []byte("name: settings")) || !bytes.Contains(output, []byte("key: log_level")) {
+		t.Errorf("应使用自定义解析策略的结果:\n%s", output)
+	}
+	for _, finding := range report.Findings {
+		if finding.Severity != utils.SeverityInfo {
+			t.Errorf("不应有警告或错误: %s", finding.String())
+		}
+	}
+
+	// 自定义策略替换内置策略：即使输入中有app-config也不会使用exact策略
+	output, report, err = Process(context.Background(), []byte(libraryConfigMap+libraryDeployment),
+		WithResolvers(lowercaseResolver{}))
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if report.Modified || bytes.Contains(output, []byte("configMapKeyRef")) {
+		t.Errorf("内置策略不应生效:\n%s", output)
+	}
+}
+
+// 配置来源与输入按优先级合并：高于输入(100)的来源覆盖输入中的同名对象，低于输入的被输入覆盖
+func TestWithSourcePriority(t *testing.T) {
+	// 来源中的app-config不包含LOG_LEVEL
+	override := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  OTHER: x\n"
+
+	tests := []struct {
+		name     string
+		priority int
+		resolved bool
+	}{
+		{"低优先级来源被输入覆盖", 50, true},
+		{"高优先级来源覆盖输入", 200, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, report, err := Process(context.Background(), []byte(libraryConfigMap+libraryDeployment),
+				WithSource(YAMLSource("override.yaml", []byte(override)), tt.priority))
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+			unresolved := false
+			for _, finding := range report.Findings {
+				if finding.Code == utils.CodeEnvUnresolved {
+					unresolved = true
+				}
+			}
+			if report.Modified != tt.resolved || unresolved == tt.resolved {
+				t.Errorf("LOG_LEVEL已解析 = %v, 期望 %v: %+v", report.Modified, tt.resolved, report.Findings)
+			}
+		})
+	}
+}
+
+// 读取失败的配置来源
+type failingSource struct{}
+
+func (failingSource) Name() string {
+	return "broken"
+}
+
+func (failingSource) Load(ctx context.Context) ([]utils.KubeResource, error) {
+	return nil, errors.New("连接被拒绝")
+}
+
+func TestProcessErrors(t *testing.T) {
+	// 引用了不存在的资源的kustomization，作为输入名称时分析失败
+	kustomizationDir := t.TempDir()
+	kustomization := filepath.Join(kustomizationDir, "kustomization.yaml")
+	if err := os.WriteFile(kustomization, []byte("resources:\n  - missing.yaml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		input      string
+		opts       []Option
+		wantOutput bool
+		check      func(t *testing.T, err error)
+	}{
+		{
+			name: "环境变量引用风格无效",
+			opts: []Option{WithEnvStyle("inline")},
+			check: func(t *testing.T, err error) {
+				var optionErr *OptionError
+				if !errors.As(err, &optionErr) || optionErr.Option != "env-style" {
+					t.Errorf("期望env-style的*OptionError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name: "未知的解析策略",
+			opts: []Option{WithResolverNames("fuzzy")},
+			check: func(t *testing.T, err error) {
+				var optionErr *OptionError
+				if !errors.As(err, &optionErr) {
+					t.Errorf("期望*OptionError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name: "空的配置来源",
+			opts: []Option{WithSource(nil, 0)},
+			check: func(t *testing.T, err error) {
+				var optionErr *OptionError
+				if !errors.As(err, &optionErr) || optionErr.Option != "source" {
+					t.Errorf("期望source的*OptionError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name: "配置来源读取失败",
+			opts: []Option{WithSource(failingSource{}, 0)},
+			check: func(t *testing.T, err error) {
+				var sourceErr *SourceError
+				if !errors.As(err, &sourceErr) || sourceErr.Source != "broken" {
+					t.Errorf("期望broken的*SourceError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name:  "处理失败",
+			input: "resources:\n  - missing.yaml\n",
+			opts:  []Option{WithName(kustomization)},
+			check: func(t *testing.T, err error) {
+				var outputErr *OutputError
+				if !errors.As(err, &outputErr) {
+					t.Errorf("期望*OutputError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name:       "达到失败条件",
+			opts:       []Option{WithFailOn(utils.FailOnChange)},
+			wantOutput: true,
+			check: func(t *testing.T, err error) {
+				var findingsErr *FindingsError
+				if !errors.As(err, &findingsErr) || findingsErr.FailOn != utils.FailOnChange || len(findingsErr.Findings) == 0 {
+					t.Errorf("期望包含发现的*FindingsError, 实际 %T: %v", err, err)
+				}
+			},
+		},
+		{
+			name: "上下文已取消",
+			ctx:  canceled,
+			check: func(t *testing.T, err error) {
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("期望context.Canceled, 实际 %T: %v", err, err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			input := tt.input
+			if input == "" {
+				input = libraryConfigMap + libraryDeployment
+			}
+
+			output, _, err := Process(ctx, []byte(input), tt.opts...)
+			if err == nil {
+				t.Fatal("期望返回错误")
+			}
+			tt.check(t, err)
+			if (output != nil) != tt.wantOutput {
+				t.Errorf("返回的内容 = %q, 期望有内容: %v", output, tt.wantOutput)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package k8sconfig
+
+import (
+	"fmt"
+
+	"github.com/k8sconfig-processor/pkg/processor"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 处理器选项
+type Option func(p *Processor) error
+
+// 输入在报告和差异中使用的名称
+func WithName(name string) Option {
+	return func(p *Processor) error {
+		p.name = name
+		return nil
+	}
+}
+
+// 添加配置来源；输入本身的优先级为100，同名对象以优先级高的为准
+func WithSource(source Source, priority int) Option {
+	return func(p *Processor) error {
+		if source == nil {
+			return &OptionError{Option: "source", Err: fmt.Errorf("配置来源不能为空")}
+		}
+		p.sources = append(p.sources, processor.PrioritySource{Source: source, Priority: priority})
+		return nil
+	}
+}
+
+// 使用自定义的解析策略，按优先级排列，替换按名称选择的内置策略
+func WithResolvers(resolvers ...Resolver) Option {
+	return func(p *Processor) error {
+		for _, resolver := range resolvers {
+			if resolver == nil {
+				return &OptionError{Option: "resolvers", Err: fmt.Errorf("解析策略不能为空")}
+			}
+		}
+		p.resolvers = resolvers
+		return nil
+	}
+}
+
+// 按名称选择内置解析策略: exact, workload, prefix, key
+func WithResolverNames(names ...string) Option {
+	return func(p *Processor) error {
+		p.options.Resolvers = names
+		return nil
+	}
+}
+
+// 前缀规则，例如 DB_ -> db-config
+func WithPrefixRules(rules map[string]string) Option {
+	return func(p *Processor) error {
+		p.options.PrefixRules = rules
+		return nil
+	}
+}
+
+// 环境变量引用风格: keyRef, envFrom, auto
+func WithEnvStyle(style string) Option {
+	return func(p *Processor) error {
+		switch style {
+		case utils.EnvStyleKeyRef, utils.EnvStyleEnvFrom, utils.EnvStyleAuto:
+		default:
+			return &OptionError{Option: "env-style", Err: fmt.Errorf("必须是 keyRef, envFrom 或 auto")}
+		}
+		p.options.EnvStyle = style
+		return nil
+	}
+}
+
+// 在pod模板上写入checksum/<名称>注解，值为引用的配置对象数据的哈希
+func WithChecksumAnnotations() Option {
+	return func(p *Processor) error {
+		p.options.ChecksumAnnotations = true
+		return nil
+	}
+}
+
+// 为未找到配置的环境变量生成值为TODO的占位ConfigMap/Secret并引用它们，占位对象追加在输出末尾
+func WithCreateMissing() Option {
+	return func(p *Processor) error {
+		p.options.CreateMissing = true
+		return nil
+	}
+}
+
+// 自定义工作负载配置文件，discover为true时在未知资源中查找containers列表
+func WithWorkloadConfig(path string, discover bool) Option {
+	return func(p *Processor) error {
+		p.options.WorkloadConfig = path
+		p.options.DiscoverContainers = discover
+		return nil
+	}
+}
+
+// 输入中有无法解析的文档时: keep（原样保留）, skip（不处理整个输入）
+func WithOnParseError(policy string) Option {
+	return func(p *Processor) error {
+		switch policy {
+		case utils.ParseErrorKeep, utils.ParseErrorSkip:
+		default:
+			return &OptionError{Option: "on-parse-error", Err: fmt.Errorf("必须是 keep 或 skip")}
+		}
+		p.options.OnParseError = policy
+		return nil
+	}
+}
+
+// 报告中有达到该级别的发现时返回*FindingsError: change, warning, error
+func WithFailOn(failOn string) Option {
+	return func(p *Processor) error {
+		switch failOn {
+		case utils.FailOnChange, utils.FailOnWarning, utils.FailOnError:
+		default:
+			return &OptionError{Option: "fail-on", Err: fmt.Errorf("必须是 change, warning 或 error")}
+		}
+		p.failOn = failOn
+		return nil
+	}
+}
+
+// 内存中的多文档YAML作为配置来源
+func YAMLSource(name string, data []byte) Source {
+	return processor.NewYAMLSource(name, data)
+}
+
+// 目录或单个YAML文件作为配置来源
+func DirectorySource(path string) Source {
+	return processor.NewDirectorySource(path)
+}
+
+// 已构造好的资源作为配置来源
+func StaticSource(name string, resources []utils.KubeResource) Source {
+	return processor.NewStaticSource(name, resources)
+}
+
+// 集群中的ConfigMap和Secret作为配置来源，只使用list权限
+func ClusterSource(client kubernetes.Interface, namespaces []string, keysOnly bool) Source {
+	return processor.NewClusterSource(client, namespaces, keysOnly)
+}
+
+// 内置解析策略
+func BuiltinResolver(name string, prefixRules map[string]string) (Resolver, error) {
+	return processor.NewResolver(name, prefixRules)
+}
@@ -0,0 +1,372 @@
+package kustomize
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// kustomization文件名，按kustomize的查找顺序排列
+var FileNames = []string{"kustomization.yaml", "kustomization.yml", utils.KustomizationFile}
+
+// 生成器的合并方式
+const (
+	BehaviorCreate  = "create"
+	BehaviorMerge   = "merge"
+	BehaviorReplace = "replace"
+)
+
+// kustomization.yaml中与配置查找相关的字段
+type Kustomization struct {
+	// 文件路径
+	Path string `yaml:"-"`
+
+	Namespace  string   `yaml:"namespace"`
+	Resources  []string `yaml:"resources"`
+	Bases      []string `yaml:"bases"`
+	Components []string `yaml:"components"`
+
+	ConfigMapGenerator []Generator `yaml:"configMapGenerator"`
+	SecretGenerator    []Generator `yaml:"secretGenerator"`
+}
+
+// configMapGenerator或secretGenerator中的一项
+type Generator struct {
+	Name      string   `yaml:"name"`
+	Namespace string   `yaml:"namespace"`
+	Behavior  string   `yaml:"behavior"`
+	Literals  []string `yaml:"literals"`
+	Envs      []string `yaml:"envs"`
+	Env       string   `yaml:"env"`
+	Files     []string `yaml:"files"`
+	Type      string   `yaml:"type"`
+}
+
+// 分析结果
+type Result struct {
+	// 资源文件（绝对路径）在各个构建中的命名空间，空字符串表示保留文档自身的命名空间
+	Namespaces map[string][]string
+	// 生成器产生的ConfigMap和Secret，名称为生成器中的名称（不含前缀和哈希后缀）
+	Resources []utils.KubeResource
+}
+
+// 判断文件是否为kustomization文件
+func IsKustomizationFile(filePath string) bool {
+	base := filepath.Base(filePath)
+	for _, name := range FileNames {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 读取目录中的kustomization文件，目录中没有kustomization文件时返回nil
+func Load(dir string) (*Kustomization, error) {
+	for _, name := range FileNames {
+		filePath := filepath.Join(dir, name)
+		if _, err := os.Stat(filePath); err == nil {
+			return LoadFile(filePath)
+		}
+	}
+	return nil, nil
+}
+
+// 读取kustomization文件
+func LoadFile(filePath string) (*Kustomization, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	kustomization := &Kustomization{}
+	if err := yaml.Unmarshal(data, kustomization); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", filePath, err)
+	}
+	kustomization.Path = filePath
+	return kustomization, nil
+}
+
+// kustomization所在目录
+func (k *Kustomization) Dir() string {
+	return filepath.Dir(k.Path)
+}
+
+// 引用的资源、基础和组件
+func (k *Kustomization) entries() []string {
+	var entries []string
+	entries = append(entries, k.Resources...)
+	entries = append(entries, k.Bases...)
+	entries = append(entries, k.Components...)
+	return entries
+}
+
+// 分析一组kustomization文件：从没有被其他kustomization引用的顶层（overlay）开始，
+// 递归展开引用的基础，记录每个资源文件的命名空间并收集生成器产生的配置
+func Analyze(kustomizationFiles []string) (*Result, error) {
+	analyzer := &analyzer{
+		loaded: make(map[string]*Kustomization),
+		result: &Result{Namespaces: make(map[string][]string)},
+	}
+
+	var all []*Kustomization
+	for _, filePath := range kustomizationFiles {
+		kustomization, err := analyzer.load(filepath.Dir(filePath))
+		if err != nil {
+			return nil, err
+		}
+		if kustomization != nil {
+			all = append(all, kustomization)
+		}
+	}
+
+	// 被引用的目录不是顶层
+	referenced := make(map[string]bool)
+	for _, kustomization := range all {
+		for _, entry := range kustomization.entries() {
+			if isRemote(entry) {
+				continue
+			}
+			referenced[parser.AbsPath(filepath.Join(kustomization.Dir(), entry))] = true
+		}
+	}
+
+	for _, kustomization := range all {
+		if referenced[parser.AbsPath(kustomization.Dir())] {
+			continue
+		}
+		generated, err := analyzer.walk(kustomization, "", make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		analyzer.result.Resources = append(analyzer.result.Resources, generated...)
+	}
+
+	return analyzer.result, nil
+}
+
+type analyzer struct {
+	// 已读取的kustomization，按目录的绝对路径索引
+	loaded map[string]*Kustomization
+	result *Result
+}
+
+// 读取目录中的kustomization，结果会被缓存
+func (a *analyzer) load(dir string) (*Kustomization, error) {
+	key := parser.AbsPath(dir)
+	if kustomization, exists := a.loaded[key]; exists {
+		return kustomization, nil
+	}
+	kustomization, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	a.loaded[key] = kustomization
+	return kustomization, nil
+}
+
+// 展开一个kustomization，外层的命名空间优先，返回生成的配置
+func (a *analyzer) walk(kustomization *Kustomization, namespace string, visiting map[string]bool) ([]utils.KubeResource, error) {
+	dir := parser.AbsPath(kustomization.Dir())
+	if visiting[dir] {
+		return nil, fmt.Errorf("kustomization循环引用: %s", kustomization.Path)
+	}
+	visiting[dir] = true
+	defer delete(visiting, dir)
+
+	if namespace == "" {
+		namespace = kustomization.Namespace
+	}
+
+	var generated []utils.KubeResource
+	for _, entry := range kustomization.entries() {
+		if isRemote(entry) {
+			continue
+		}
+
+		entryPath := filepath.Join(kustomization.Dir(), entry)
+		info, err := os.Stat(entryPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s 引用的资源不存在: %s", kustomization.Path, entry)
+		}
+
+		if !info.IsDir() {
+			a.addNamespace(parser.AbsPath(entryPath), namespace)
+			continue
+		}
+
+		base, err := a.load(entryPath)
+		if err != nil {
+			return nil, err
+		}
+		if base == nil {
+			continue
+		}
+		baseGenerated, err := a.walk(base, namespace, visiting)
+		if err != nil {
+			return nil, err
+		}
+		generated = append(generated, baseGenerated...)
+	}
+
+	for _, generator := range kustomization.ConfigMapGenerator {
+		resource, err := generator.resource(utils.ConfigMapKind, kustomization.Dir(), namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", kustomization.Path, err)
+		}
+		if generated, err = applyGenerated(generated, resource, generator.Behavior); err != nil {
+			return nil, fmt.Errorf("%s: %v", kustomization.Path, err)
+		}
+	}
+	for _, generator := range kustomization.SecretGenerator {
+		resource, err := generator.resource(utils.SecretKind, kustomization.Dir(), namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", kustomization.Path, err)
+		}
+		if generated, err = applyGenerated(generated, resource, generator.Behavior); err != nil {
+			return nil, fmt.Errorf("%s: %v", kustomization.Path, err)
+		}
+	}
+
+	return generated, nil
+}
+
+// 记录资源文件在一次构建中的命名空间
+func (a *analyzer) addNamespace(filePath, namespace string) {
+	for _, existing := range a.result.Namespaces[filePath] {
+		if existing == namespace {
+			return
+		}
+	}
+	a.result.Namespaces[filePath] = append(a.result.Namespaces[filePath], namespace)
+}
+
+// 按生成器的合并方式加入生成的配置：create（默认）要求同名对象不存在，
+// merge合并到基础中的同名对象，replace替换同名对象，与kustomize一样同名对象不存在时返回错误
+func applyGenerated(generated []utils.KubeResource, resource utils.KubeResource, behavior string) ([]utils.KubeResource, error) {
+	if behavior == "" {
+		behavior = BehaviorCreate
+	}
+	if behavior != BehaviorCreate && behavior != BehaviorMerge && behavior != BehaviorReplace {
+		return nil, fmt.Errorf("生成器 %s 的behavior无效: %s", resource.Metadata.Name, behavior)
+	}
+
+	for i, existing := range generated {
+		if existing.Kind != resource.Kind || existing.Metadata.Name != resource.Metadata.Name {
+			continue
+		}
+		if behavior == BehaviorCreate {
+			return nil, fmt.Errorf("生成器 %s 与已有的%s冲突，覆盖基础中的对象需要设置behavior为merge或replace", resource.Metadata.Name, resource.Kind)
+		}
+		if behavior == BehaviorMerge {
+			for key, value := range resource.Data {
+				existing.Data[key] = value
+			}
+			for key, value := range resource.StringData {
+				existing.StringData[key] = value
+			}
+			resource = existing
+		}
+		generated[i] = resource
+		return generated, nil
+	}
+	if behavior != BehaviorCreate {
+		return nil, fmt.Errorf("生成器 %s 的behavior为%s，但不存在同名的%s", resource.Metadata.Name, behavior, resource.Kind)
+	}
+	return append(generated, resource), nil
+}
+
+// 生成ConfigMap或Secret，字面值、env文件和文件均相对于kustomization所在目录
+func (g Generator) resource(kind, dir, namespace string) (utils.KubeResource, error) {
+	var resource utils.KubeResource
+	resource.APIVersion = "v1"
+	resource.Kind = kind
+	resource.Type = g.Type
+	resource.Metadata.Name = g.Name
+
+	if namespace == "" {
+		namespace = g.Namespace
+	}
+	if namespace == "" {
+		namespace = utils.DefaultNamespace
+	}
+	resource.Metadata.Namespace = namespace
+
+	if g.Name == "" {
+		return resource, fmt.Errorf("%s生成器缺少name字段", kind)
+	}
+
+	data := make(map[string]string)
+	for _, literal := range g.Literals {
+		key, value, found := strings.Cut(literal, "=")
+		if !found || key == "" {
+			return resource, fmt.Errorf("生成器 %s 的字面值格式无效: %s", g.Name, literal)
+		}
+		data[key] = strings.Trim(value, `"'`)
+	}
+
+	envs := g.Envs
+	if g.Env != "" {
+		envs = append(envs, g.Env)
+	}
+	for _, envFile := range envs {
+		if err := readEnvFile(filepath.Join(dir, envFile), data); err != nil {
+			return resource, fmt.Errorf("生成器 %s 读取env文件失败: %v", g.Name, err)
+		}
+	}
+
+	for _, file := range g.Files {
+		// 格式为 PATH 或 KEY=PATH，未指定键时使用文件名
+		key, filePath, found := strings.Cut(file, "=")
+		if !found {
+			key, filePath = filepath.Base(file), file
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filePath))
+		if err != nil {
+			return resource, fmt.Errorf("生成器 %s 读取文件失败: %v", g.Name, err)
+		}
+		data[key] = string(content)
+	}
+
+	// Secret的值直接写入stringData，不需要编码
+	if kind == utils.SecretKind {
+		resource.StringData = data
+	} else {
+		resource.Data = data
+	}
+	return resource, nil
+}
+
+// 读取env文件：KEY=VALUE格式，忽略空行和注释；只有键的行在kustomize中从环境变量读取，这里跳过
+func readEnvFile(filePath string, data map[string]string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		data[strings.TrimSpace(key)] = value
+	}
+	return scanner.Err()
+}
+
+// 判断是否为远程资源（git仓库或URL），远程资源不做展开
+func isRemote(entry string) bool {
+	return strings.Contains(entry, "://") || strings.HasPrefix(entry, "github.com/") || strings.HasPrefix(entry, "git@")
+}
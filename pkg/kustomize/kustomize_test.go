@@ -0,0 +1,276 @@
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 在临时目录中按相对路径写入文件
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// 按相对路径返回kustomization文件的绝对路径
+func kustomizationFiles(root string, dirs ...string) []string {
+	var files []string
+	for _, dir := range dirs {
+		files = append(files, filepath.Join(root, dir, "kustomization.yaml"))
+	}
+	return files
+}
+
+// 查找生成的配置
+func findResource(resources []utils.KubeResource, kind, name string) *utils.KubeResource {
+	for i := range resources {
+		if resources[i].Kind == kind && resources[i].Metadata.Name == name {
+			return &resources[i]
+		}
+	}
+	return nil
+}
+
+func TestAnalyzeOverlayWalk(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"base/kustomization.yaml": `resources:
+  - deployment.yaml
+  - https://github.com/example/remote//config
+configMapGenerator:
+  - name: app-config
+    literals:
+      - LOG_LEVEL=info
+`,
+		"base/deployment.yaml": "kind: Deployment\n",
+		"overlays/dev/kustomization.yaml": `resources:
+  - ../../base
+  - extra.yaml
+`,
+		"overlays/dev/extra.yaml": "kind: Service\n",
+	})
+
+	// base被overlay引用，不是顶层，只展开一次
+	result, err := Analyze(kustomizationFiles(root, "base", "overlays/dev"))
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	wantNamespaces := map[string][]string{
+		filepath.Join(root, "base", "deployment.yaml"):       {""},
+		filepath.Join(root, "overlays", "dev", "extra.yaml"): {""},
+	}
+	if !reflect.DeepEqual(result.Namespaces, wantNamespaces) {
+		t.Errorf("Namespaces = %v, 期望 %v", result.Namespaces, wantNamespaces)
+	}
+
+	if len(result.Resources) != 1 {
+		t.Fatalf("生成了%d个配置, 期望1个: %+v", len(result.Resources), result.Resources)
+	}
+	resource := result.Resources[0]
+	if resource.Kind != utils.ConfigMapKind || resource.Metadata.Name != "app-config" ||
+		resource.Metadata.Namespace != utils.DefaultNamespace || resource.Data["LOG_LEVEL"] != "info" {
+		t.Errorf("生成的配置不正确: %+v", resource)
+	}
+}
+
+func TestAnalyzeCycle(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a/kustomization.yaml":   "resources:\n  - ../b\n",
+		"b/kustomization.yaml":   "resources:\n  - ../a\n",
+		"top/kustomization.yaml": "resources:\n  - ../a\n",
+	})
+
+	if _, err := Analyze(kustomizationFiles(root, "top", "a", "b")); err == nil {
+		t.Error("循环引用应返回错误")
+	}
+}
+
+func TestAnalyzeGeneratorBehavior(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{"合并", BehaviorMerge, map[string]string{"LOG_LEVEL": "debug", "PORT": "8080", "DEBUG": "true"}, false},
+		{"替换", BehaviorReplace, map[string]string{"LOG_LEVEL": "debug", "DEBUG": "true"}, false},
+		// 与kustomize一样，create不能覆盖基础中的同名对象
+		{"默认创建与基础冲突", "", nil, true},
+		{"创建与基础冲突", BehaviorCreate, nil, true},
+		{"无效的behavior", "upsert", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, map[string]string{
+				"base/kustomization.yaml": `configMapGenerator:
+  - name: app-config
+    literals:
+      - LOG_LEVEL=info
+      - PORT=8080
+secretGenerator:
+  - name: app-secret
+    envs:
+      - secret.env
+`,
+				"base/secret.env": "# 注释\nPASSWORD=base\n\nTOKEN=abc\n",
+				"overlay/kustomization.yaml": `resources:
+  - ../base
+configMapGenerator:
+  - name: app-config
+    behavior: ` + tt.behavior + `
+    literals:
+      - LOG_LEVEL=debug
+      - DEBUG="true"
+secretGenerator:
+  - name: app-secret
+    behavior: merge
+    literals:
+      - PASSWORD=overlay
+`,
+			})
+
+			result, err := Analyze(kustomizationFiles(root, "overlay"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Analyze() 错误 = %v, 期望错误: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			configMap := findResource(result.Resources, utils.ConfigMapKind, "app-config")
+			if configMap == nil {
+				t.Fatalf("缺少app-config: %+v", result.Resources)
+			}
+			if !reflect.DeepEqual(configMap.Data, tt.want) {
+				t.Errorf("Data = %v, 期望 %v", configMap.Data, tt.want)
+			}
+
+			secret := findResource(result.Resources, utils.SecretKind, "app-secret")
+			if secret == nil {
+				t.Fatalf("缺少app-secret: %+v", result.Resources)
+			}
+			wantSecret := map[string]string{"PASSWORD": "overlay", "TOKEN": "abc"}
+			if !reflect.DeepEqual(secret.StringData, wantSecret) {
+				t.Errorf("StringData = %v, 期望 %v", secret.StringData, wantSecret)
+			}
+
+			if len(result.Resources) != 2 {
+				t.Errorf("同名生成器应合并为一个对象, 实际生成%d个", len(result.Resources))
+			}
+		})
+	}
+}
+
+// merge和replace要求存在同名对象，同一kustomization中重复创建同样冲突
+func TestAnalyzeGeneratorMissingBase(t *testing.T) {
+	tests := []struct {
+		name          string
+		kustomization string
+	}{
+		{"合并不存在的对象", "configMapGenerator:\n  - name: app-config\n    behavior: merge\n    literals:\n      - A=1\n"},
+		{"替换不存在的对象", "secretGenerator:\n  - name: app-secret\n    behavior: replace\n    literals:\n      - A=1\n"},
+		{"重复创建", "configMapGenerator:\n  - name: app-config\n    literals:\n      - A=1\n  - name: app-config\n    literals:\n      - B=2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, map[string]string{"app/kustomization.yaml": tt.kustomization})
+			if _, err := Analyze(kustomizationFiles(root, "app")); err == nil {
+				t.Error("期望返回错误")
+			}
+		})
+	}
+}
+
+// 没有扩展名的Kustomization文件与kustomization.yaml同样作为顶层分析
+func TestAnalyzeKustomizationFileName(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app/Kustomization":   "namespace: prod\nresources:\n  - deployment.yaml\n",
+		"app/deployment.yaml": "kind: Deployment\n",
+	})
+
+	kustomization := filepath.Join(root, "app", utils.KustomizationFile)
+	if !IsKustomizationFile(kustomization) {
+		t.Fatalf("%s 应为kustomization文件", kustomization)
+	}
+	result, err := Analyze([]string{kustomization})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	deployment := filepath.Join(root, "app", "deployment.yaml")
+	if got := result.Namespaces[deployment]; !reflect.DeepEqual(got, []string{"prod"}) {
+		t.Errorf("命名空间 = %v, 期望 [prod]", got)
+	}
+}
+
+func TestAnalyzeNamespacePrecedence(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"base/kustomization.yaml": `namespace: base-ns
+resources:
+  - deployment.yaml
+configMapGenerator:
+  - name: app-config
+    namespace: generator-ns
+    literals:
+      - A=1
+`,
+		"base/deployment.yaml": "kind: Deployment\n",
+		// 外层overlay的命名空间覆盖基础中的命名空间
+		"overlays/prod/kustomization.yaml": "namespace: prod\nresources:\n  - ../../base\n",
+		// 没有设置命名空间的overlay沿用基础的命名空间
+		"overlays/plain/kustomization.yaml": "resources:\n  - ../../base\n",
+		// 两层overlay时最外层优先，prod被prod-eu引用，不单独构建
+		"overlays/prod-eu/kustomization.yaml": "namespace: prod-eu\nresources:\n  - ../prod\n",
+		// 各层都没有命名空间时使用生成器自身的命名空间
+		"solo/kustomization.yaml": `configMapGenerator:
+  - name: solo-config
+    namespace: generator-ns
+    literals:
+      - A=1
+`,
+	})
+
+	result, err := Analyze(kustomizationFiles(root, "base", "overlays/prod", "overlays/plain", "overlays/prod-eu", "solo"))
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	got := append([]string(nil), result.Namespaces[filepath.Join(root, "base", "deployment.yaml")]...)
+	sort.Strings(got)
+	want := []string{"base-ns", "prod-eu"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deployment.yaml的命名空间 = %v, 期望 %v", got, want)
+	}
+
+	var namespaces []string
+	for _, resource := range result.Resources {
+		if resource.Metadata.Name == "app-config" {
+			namespaces = append(namespaces, resource.Metadata.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	wantNamespaces := []string{"base-ns", "prod-eu"}
+	if !reflect.DeepEqual(namespaces, wantNamespaces) {
+		t.Errorf("app-config的命名空间 = %v, 期望 %v", namespaces, wantNamespaces)
+	}
+
+	solo := findResource(result.Resources, utils.ConfigMapKind, "solo-config")
+	if solo == nil || solo.Metadata.Namespace != "generator-ns" {
+		t.Errorf("solo-config应使用生成器的命名空间: %+v", solo)
+	}
+}
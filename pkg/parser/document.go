@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"bytes"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 解析后的YAML文件
+type File struct {
+	// 文件路径
+	Path string
+	// 原始文件内容
+	Content []byte
+	// 文件中的文档，按原始顺序排列
+	Documents []*Document
+}
+
+// 判断文件中是否有无法解析的文档
+func (f *File) HasErrors() bool {
+	for _, document := range f.Documents {
+		if document.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// 文件中的单个YAML文档
+type Document struct {
+	// 所在文件的路径
+	Path string
+	// 文档节点树，处理器直接在其上修改
+	Node *yaml.Node
+	// 解析出的资源元数据
+	Resource utils.KubeResource
+	// 原始文档内容（包含前导的分隔符行）
+	Raw []byte
+	// 文档在文件中的起始行号（从1开始）
+	StartLine int
+	// 解析错误，非空时Node为nil，文档按原始内容保留
+	Err error
+
+	// 解析时的节点树副本，用于计算修改
+	original *yaml.Node
+}
+
+// 获取文档的根节点，空文档返回nil
+func (d *Document) Root() *yaml.Node {
+	if d.Node == nil || d.Node.Kind != yaml.DocumentNode || len(d.Node.Content) == 0 {
+		return nil
+	}
+	return d.Node.Content[0]
+}
+
+// 获取节点在文件中的行号
+func (d *Document) Line(node *yaml.Node) int {
+	if node == nil || node.Line == 0 {
+		return 0
+	}
+	return d.StartLine + node.Line - 1
+}
+
+// 判断文档节点树是否被修改
+func (d *Document) Modified() bool {
+	return d.Node != nil && d.original != nil && !NodesEqual(d.original, d.Node)
+}
+
+// 将文件按文档分隔符拆分，各部分拼接后与原始内容完全一致
+func splitDocuments(content []byte) [][]byte {
+	var chunks [][]byte
+	start := 0
+	offset := 0
+
+	for offset < len(content) {
+		lineEnd := bytes.IndexByte(content[offset:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += offset + 1
+		}
+
+		// 分隔符行开始一个新文档
+		if offset > start && isDocumentSeparator(content[offset:lineEnd]) {
+			chunks = append(chunks, content[start:offset])
+			start = offset
+		}
+		offset = lineEnd
+	}
+
+	if start < len(content) || len(chunks) == 0 {
+		chunks = append(chunks, content[start:])
+	}
+
+	return chunks
+}
+
+// 判断是否为文档分隔符行
+func isDocumentSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+	rest := line[3:]
+	return len(rest) == 0 || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r'
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// 在映射节点中查找指定键的值
+func MappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// 在映射节点中设置指定键的值，键不存在时追加到末尾
+func SetMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, NewScalar(key), value)
+}
+
+// 从映射节点中删除指定键，返回是否删除
+func DeleteMappingValue(node *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// 获取映射节点中指定键的字符串值
+func MappingString(node *yaml.Node, key string) (string, bool) {
+	value := MappingValue(node, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return value.Value, true
+}
+
+// 按路径查找节点
+func LookupPath(node *yaml.Node, path ...string) *yaml.Node {
+	for _, key := range path {
+		node = MappingValue(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// 创建字符串标量节点
+func NewScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// 创建映射节点，参数依次为键和值
+func NewMapping(pairs ...interface{}) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key := pairs[i].(string)
+		switch value := pairs[i+1].(type) {
+		case *yaml.Node:
+			node.Content = append(node.Content, NewScalar(key), value)
+		case string:
+			node.Content = append(node.Content, NewScalar(key), NewScalar(value))
+		}
+	}
+	return node
+}
+
+// 深拷贝节点树
+func CopyNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	copied := *node
+	if node.Content != nil {
+		copied.Content = make([]*yaml.Node, len(node.Content))
+		for i, child := range node.Content {
+			copied.Content[i] = CopyNode(child)
+		}
+	}
+	return &copied
+}
+
+// 比较两个节点树的内容是否相同（忽略位置信息）
+func NodesEqual(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || a.Value != b.Value || a.Style != b.Style ||
+		a.Anchor != b.Anchor || a.ShortTag() != b.ShortTag() ||
+		a.HeadComment != b.HeadComment || a.LineComment != b.LineComment ||
+		a.FootComment != b.FootComment || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !NodesEqual(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
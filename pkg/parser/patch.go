@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSON Patch操作（RFC 6902）
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// 计算文档从原始内容到当前内容的JSON Patch
+func (d *Document) JSONPatch() ([]PatchOperation, error) {
+	if !d.Modified() {
+		return nil, nil
+	}
+
+	builder := &patchBuilder{}
+	if err := builder.diff(rootOf(d.original), rootOf(d.Node), ""); err != nil {
+		return nil, err
+	}
+	return builder.operations, nil
+}
+
+// JSON Patch生成器
+type patchBuilder struct {
+	operations []PatchOperation
+}
+
+// 对比两个节点，生成把orig变为mod的操作
+func (b *patchBuilder) diff(orig, mod *yaml.Node, path string) error {
+	if NodesEqual(orig, mod) {
+		return nil
+	}
+
+	if orig != nil && mod != nil && orig.Kind == mod.Kind {
+		switch orig.Kind {
+		case yaml.MappingNode:
+			return b.diffMapping(orig, mod, path)
+		case yaml.SequenceNode:
+			return b.diffSequence(orig, mod, path)
+		case yaml.ScalarNode:
+			// 只有样式或注释不同时值不变
+			if orig.Value == mod.Value && orig.ShortTag() == mod.ShortTag() {
+				return nil
+			}
+		}
+	}
+
+	return b.add("replace", path, mod)
+}
+
+// 对比映射节点
+func (b *patchBuilder) diffMapping(orig, mod *yaml.Node, path string) error {
+	for i := 0; i+1 < len(orig.Content); i += 2 {
+		key := orig.Content[i].Value
+		if MappingValue(mod, key) == nil {
+			b.operations = append(b.operations, PatchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+		}
+	}
+
+	for i := 0; i+1 < len(mod.Content); i += 2 {
+		key := mod.Content[i].Value
+		origValue := MappingValue(orig, key)
+		if origValue == nil {
+			if err := b.add("add", path+"/"+escapePointer(key), mod.Content[i+1]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.diff(origValue, mod.Content[i+1], path+"/"+escapePointer(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 对比序列节点，使用与渲染相同的对齐方式，下标按操作依次生效计算
+func (b *patchBuilder) diffSequence(orig, mod *yaml.Node, path string) error {
+	matched := alignNodes(orig.Content, mod.Content, NodesEqual)
+	matched = refineAlignment(orig.Content, mod.Content, matched, sameNamedItem)
+
+	index := 0
+	next := 0
+	for j, i := range matched {
+		if i < 0 {
+			if err := b.add("add", path+"/"+strconv.Itoa(index), mod.Content[j]); err != nil {
+				return err
+			}
+			index++
+			continue
+		}
+
+		for k := next; k < i; k++ {
+			b.operations = append(b.operations, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(index)})
+		}
+		next = i + 1
+
+		if err := b.diff(orig.Content[i], mod.Content[j], path+"/"+strconv.Itoa(index)); err != nil {
+			return err
+		}
+		index++
+	}
+
+	for k := next; k < len(orig.Content); k++ {
+		b.operations = append(b.operations, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(index)})
+	}
+
+	return nil
+}
+
+// 添加带值的操作
+func (b *patchBuilder) add(op, path string, node *yaml.Node) error {
+	var value interface{}
+	if node != nil {
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+	}
+	b.operations = append(b.operations, PatchOperation{Op: op, Path: path, Value: value})
+	return nil
+}
+
+// 转义JSON Pointer中的特殊字符
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
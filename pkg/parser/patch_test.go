@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const patchInput = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    example.com/owner~team: payments
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+              value: info
+            - name: REMOVE_ME
+              value: old
+            - name: DB_HOST
+            - name: REMOVE_ME
+              value: older
+            - name: PORT
+`
+
+func TestJSONPatch(t *testing.T) {
+	const env = "/spec/template/spec/containers/0/env"
+	valueFrom := func(key string) interface{} {
+		return map[string]interface{}{"configMapKeyRef": map[string]interface{}{"name": "app-config", "key": key}}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(node *yaml.Node)
+		want   []PatchOperation
+	}{
+		{
+			name:   "未修改",
+			mutate: func(node *yaml.Node) {},
+		},
+		{
+			name:   "插入valueFrom并删除环境变量",
+			mutate: mutateEnv,
+			// 下标按操作依次生效计算：删除第1项后DB_HOST位于下标1
+			want: []PatchOperation{
+				{Op: "remove", Path: env + "/1"},
+				{Op: "add", Path: env + "/1/valueFrom", Value: valueFrom("DB_HOST")},
+				{Op: "remove", Path: env + "/2"},
+				{Op: "add", Path: env + "/2/valueFrom", Value: valueFrom("PORT")},
+			},
+		},
+		{
+			name: "替换值",
+			mutate: func(node *yaml.Node) {
+				envVar := LookupPath(rootOf(node), "spec", "template", "spec", "containers").Content[0]
+				SetMappingValue(MappingValue(envVar, "env").Content[0], "value", NewScalar("debug"))
+			},
+			want: []PatchOperation{{Op: "replace", Path: env + "/0/value", Value: "debug"}},
+		},
+		{
+			name: "键中的/和~需要转义",
+			mutate: func(node *yaml.Node) {
+				annotations := LookupPath(rootOf(node), "metadata", "annotations")
+				DeleteMappingValue(annotations, "example.com/owner~team")
+				SetMappingValue(annotations, "checksum/app-config", NewScalar("abc"))
+			},
+			want: []PatchOperation{
+				{Op: "remove", Path: "/metadata/annotations/example.com~1owner~0team"},
+				{Op: "add", Path: "/metadata/annotations/checksum~1app-config", Value: "abc"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+			document := parser.ParseContent("app.yaml", []byte(patchInput)).Documents[0]
+			tt.mutate(document.Node)
+
+			operations, err := document.JSONPatch()
+			if err != nil {
+				t.Fatalf("JSONPatch: %v", err)
+			}
+			if !reflect.DeepEqual(normalize(t, operations), normalize(t, tt.want)) {
+				t.Errorf("JSONPatch() =\n%s\n期望:\n%s", toJSON(t, operations), toJSON(t, tt.want))
+			}
+
+			// 按顺序应用到原始文档后与修改后的文档一致
+			var original, modified interface{}
+			if err := document.original.Decode(&original); err != nil {
+				t.Fatal(err)
+			}
+			if err := document.Node.Decode(&modified); err != nil {
+				t.Fatal(err)
+			}
+			patched, err := applyPatch(original, operations)
+			if err != nil {
+				t.Fatalf("应用JSON Patch失败: %v", err)
+			}
+			if !reflect.DeepEqual(patched, modified) {
+				t.Errorf("应用JSON Patch后的结果与修改后的文档不一致:\n%s", toJSON(t, patched))
+			}
+		})
+	}
+}
+
+// 经过JSON编解码，统一值的类型后再比较
+func normalize(t *testing.T, operations []PatchOperation) interface{} {
+	t.Helper()
+	var result interface{}
+	if err := json.Unmarshal([]byte(toJSON(t, operations)), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func toJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// 按RFC 6902依次应用add、remove和replace操作
+func applyPatch(doc interface{}, operations []PatchOperation) (interface{}, error) {
+	for _, operation := range operations {
+		if !strings.HasPrefix(operation.Path, "/") {
+			return nil, fmt.Errorf("无效的路径: %s", operation.Path)
+		}
+		var tokens []string
+		for _, token := range strings.Split(operation.Path[1:], "/") {
+			tokens = append(tokens, strings.NewReplacer("~1", "/", "~0", "~").Replace(token))
+		}
+
+		var err error
+		doc, err = applyOperation(doc, tokens, operation)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", operation.Op, operation.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(node interface{}, tokens []string, operation PatchOperation) (interface{}, error) {
+	token, last := tokens[0], len(tokens) == 1
+
+	switch container := node.(type) {
+	case map[string]interface{}:
+		child, exists := container[token]
+		if !last {
+			if !exists {
+				return nil, fmt.Errorf("键 %s 不存在", token)
+			}
+			updated, err := applyOperation(child, tokens[1:], operation)
+			container[token] = updated
+			return container, err
+		}
+		switch operation.Op {
+		case "add":
+			container[token] = operation.Value
+		case "replace", "remove":
+			if !exists {
+				return nil, fmt.Errorf("键 %s 不存在", token)
+			}
+			if operation.Op == "remove" {
+				delete(container, token)
+			} else {
+				container[token] = operation.Value
+			}
+		}
+		return container, nil
+
+	case []interface{}:
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(container) || (index == len(container) && operation.Op != "add") {
+			return nil, fmt.Errorf("下标 %s 无效", token)
+		}
+		if !last {
+			updated, err := applyOperation(container[index], tokens[1:], operation)
+			container[index] = updated
+			return container, err
+		}
+		switch operation.Op {
+		case "add":
+			container = append(container[:index], append([]interface{}{operation.Value}, container[index:]...)...)
+		case "remove":
+			container = append(container[:index], container[index+1:]...)
+		case "replace":
+			container[index] = operation.Value
+		}
+		return container, nil
+	}
+
+	return nil, fmt.Errorf("路径经过非容器节点")
+}
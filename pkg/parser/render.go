@@ -0,0 +1,771 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 无法从原文件判断时使用的缩进宽度
+const defaultIndent = 2
+
+// 对原始内容的一处改写
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+// 文档渲染器：对比原始节点树与修改后的节点树，只改写发生变化的片段，
+// 其余内容（注释、键顺序、缩进、引号风格）保持与原文件逐字节一致
+type renderer struct {
+	src        []byte
+	lineStarts []int
+	edits      []edit
+	err        error
+
+	// 原文件的换行符、缩进宽度和序列风格，新写入的内容与之保持一致
+	newline string
+	indent  int
+	compact bool
+}
+
+// 将文档渲染为YAML，未修改的文档原样返回
+func (d *Document) Render() ([]byte, error) {
+	if !d.Modified() {
+		return d.Raw, nil
+	}
+
+	origRoot := rootOf(d.original)
+	modRoot := d.Root()
+	if origRoot == nil || modRoot == nil {
+		return d.encodeAll()
+	}
+
+	r := newRenderer(d.Raw, detectIndent(origRoot))
+	r.compact = detectCompact(origRoot)
+	if !r.diff(origRoot, modRoot, len(r.src)) {
+		return d.encodeAll()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return r.apply(), nil
+}
+
+// 整体重新编码文档，保留原有的分隔符行
+func (d *Document) encodeAll() ([]byte, error) {
+	newline := detectNewline(d.Raw)
+
+	var buf bytes.Buffer
+	if isDocumentSeparator(d.Raw) {
+		lineEnd := bytes.IndexByte(d.Raw, '\n')
+		if lineEnd < 0 {
+			buf.Write(d.Raw)
+			buf.WriteString(newline)
+		} else {
+			buf.Write(d.Raw[:lineEnd+1])
+		}
+	}
+
+	text, err := encodeNode(d.Node, detectIndent(rootOf(d.original)))
+	if err != nil {
+		return nil, err
+	}
+	if detectCompact(rootOf(d.original)) {
+		text = compactSequences(text)
+	}
+	buf.WriteString(withNewline(text, newline))
+
+	return buf.Bytes(), nil
+}
+
+func rootOf(node *yaml.Node) *yaml.Node {
+	if node == nil || node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return nil
+	}
+	return node.Content[0]
+}
+
+func newRenderer(src []byte, indent int) *renderer {
+	lineStarts := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &renderer{src: src, lineStarts: lineStarts, newline: detectNewline(src), indent: indent}
+}
+
+// 按第一个换行符判断原文件使用LF还是CRLF
+func detectNewline(src []byte) string {
+	if i := bytes.IndexByte(src, '\n'); i > 0 && src[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// 将编码器输出的LF换行转换为原文件的换行符
+func withNewline(text, newline string) string {
+	if newline == "\n" {
+		return text
+	}
+	return strings.ReplaceAll(text, "\n", newline)
+}
+
+// 按第一个嵌套的块映射判断原文件的缩进宽度，无法判断时使用两个空格
+func detectIndent(node *yaml.Node) int {
+	if indent, ok := findIndent(node); ok {
+		return indent
+	}
+	return defaultIndent
+}
+
+// 按文档顺序查找第一个与父键不在同一行的块映射，返回它相对父键的缩进
+func findIndent(node *yaml.Node) (int, bool) {
+	if node == nil {
+		return 0, false
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.MappingNode && isBlockCollection(value) && value.Line > key.Line {
+				if step := value.Content[0].Column - key.Column; step > 0 {
+					return step, true
+				}
+			}
+			if indent, ok := findIndent(value); ok {
+				return indent, true
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if indent, ok := findIndent(item); ok {
+				return indent, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// 按文档顺序查找第一个与父键不在同一行的块序列，短横线与父键对齐时为紧凑风格
+func detectCompact(node *yaml.Node) bool {
+	compact, _ := findSequenceStyle(node)
+	return compact
+}
+
+func findSequenceStyle(node *yaml.Node) (compact bool, ok bool) {
+	if node == nil {
+		return false, false
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.SequenceNode && isBlockCollection(value) && value.Line > key.Line {
+				return value.Column == key.Column, true
+			}
+			if compact, ok := findSequenceStyle(value); ok {
+				return compact, true
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if compact, ok := findSequenceStyle(item); ok {
+				return compact, true
+			}
+		}
+	}
+	return false, false
+}
+
+// 块标量的头部，如 "key: |-"、"- >" 或 "key: |2"
+var blockScalarHeader = regexp.MustCompile(`(^|[:-] )[|>][1-9]?[-+]?[1-9]?$`)
+
+// 编码器总是缩进映射中的块序列，将其改为短横线与父键对齐的紧凑风格。
+// 块标量的内容随其所在行整体移动，不作为结构解析
+func compactSequences(text string) string {
+	type block struct{ parent, shift int }
+	var stack []block
+	scalar, scalarShift := -1, 0
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		if scalar >= 0 {
+			if indent > scalar {
+				lines[i] = line[scalarShift:]
+				continue
+			}
+			scalar = -1
+		}
+
+		for len(stack) > 0 && indent <= stack[len(stack)-1].parent {
+			stack = stack[:len(stack)-1]
+		}
+		shift := 0
+		for _, b := range stack {
+			shift += b.shift
+		}
+		lines[i] = line[shift:]
+
+		if blockScalarHeader.MatchString(trimmed) {
+			scalar, scalarShift = indent, shift
+			continue
+		}
+		if !strings.HasSuffix(trimmed, ":") || i+1 >= len(lines) {
+			continue
+		}
+		// 键所在的列，跳过行首的序列项短横线
+		column := indent
+		for strings.HasPrefix(trimmed, "- ") {
+			trimmed = strings.TrimPrefix(trimmed, "- ")
+			column += 2
+		}
+		next := strings.TrimLeft(lines[i+1], " ")
+		nextIndent := len(lines[i+1]) - len(next)
+		if nextIndent > column && (strings.HasPrefix(next, "- ") || next == "-") {
+			stack = append(stack, block{parent: column, shift: nextIndent - column})
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 按位置顺序应用所有改写
+func (r *renderer) apply() []byte {
+	sort.SliceStable(r.edits, func(i, j int) bool {
+		if r.edits[i].start != r.edits[j].start {
+			return r.edits[i].start < r.edits[j].start
+		}
+		return r.edits[i].end-r.edits[i].start < r.edits[j].end-r.edits[j].start
+	})
+
+	var buf bytes.Buffer
+	pos := 0
+	for _, e := range r.edits {
+		buf.Write(r.src[pos:e.start])
+		buf.WriteString(e.text)
+		pos = e.end
+	}
+	buf.Write(r.src[pos:])
+
+	return buf.Bytes()
+}
+
+// 对比两个节点，生成必要的改写，无法原地改写时返回false
+func (r *renderer) diff(orig, mod *yaml.Node, limit int) bool {
+	if NodesEqual(orig, mod) {
+		return true
+	}
+
+	if orig.Kind == mod.Kind && isBlockCollection(orig) && isBlockCollection(mod) &&
+		orig.Anchor == mod.Anchor && orig.ShortTag() == mod.ShortTag() &&
+		orig.HeadComment == mod.HeadComment && orig.LineComment == mod.LineComment &&
+		orig.FootComment == mod.FootComment {
+		mark := len(r.edits)
+		var ok bool
+		if orig.Kind == yaml.MappingNode {
+			ok = r.patchMapping(orig, mod, limit)
+		} else {
+			ok = r.patchSequence(orig, mod, limit)
+		}
+		if ok {
+			return true
+		}
+		r.edits = r.edits[:mark]
+	}
+
+	return r.replaceNode(orig, mod, limit)
+}
+
+// 用重新编码的内容替换整个节点
+func (r *renderer) replaceNode(orig, mod *yaml.Node, limit int) bool {
+	start := r.offset(orig)
+
+	// 块集合只能出现在行首或序列项标记之后
+	if isBlockCollection(mod) && !r.blockAllowedAt(start) {
+		return false
+	}
+
+	var end int
+	if orig.Kind == yaml.ScalarNode {
+		end = r.scalarEnd(orig, limit)
+		if orig.LineComment == mod.LineComment {
+			// 原有的行尾注释保留在原文中
+			stripped := *mod
+			stripped.LineComment = ""
+			mod = &stripped
+		} else if orig.LineComment != "" {
+			end = r.trimEnd(start, r.lineEnd(end), false)
+		}
+	} else {
+		end = r.trimEnd(start, limit, false)
+	}
+
+	r.replace(start, end, r.renderInline(mod, orig.Column))
+	return true
+}
+
+// 逐个键对比映射节点
+func (r *renderer) patchMapping(orig, mod *yaml.Node, limit int) bool {
+	count := len(orig.Content) / 2
+
+	// 计算原始键值对的位置
+	keyStarts := make([]int, count)
+	contentEnds := make([]int, count)
+	boundaries := make([]int, count)
+	for i := 0; i < count; i++ {
+		keyStarts[i] = r.offset(orig.Content[2*i])
+	}
+	for i := 0; i < count; i++ {
+		boundaries[i] = limit
+		if i+1 < count {
+			boundaries[i] = r.lineStart(keyStarts[i+1])
+		}
+		contentEnds[i] = r.trimEnd(keyStarts[i], boundaries[i], isBlockScalar(orig.Content[2*i+1]))
+	}
+
+	// 按键名匹配，已有键的相对顺序不能改变
+	matched := make([]int, len(mod.Content)/2)
+	last := -1
+	for j := range matched {
+		matched[j] = -1
+		key := mod.Content[2*j]
+		for i := 0; i < count; i++ {
+			if orig.Content[2*i].Kind == yaml.ScalarNode && key.Kind == yaml.ScalarNode &&
+				orig.Content[2*i].Value == key.Value {
+				if i <= last {
+					return false
+				}
+				matched[j] = i
+				last = i
+				break
+			}
+		}
+	}
+	if last < 0 {
+		return false
+	}
+
+	var pending []*yaml.Node
+	next := 0
+	retained := -1
+	for j, i := range matched {
+		modKey, modValue := mod.Content[2*j], mod.Content[2*j+1]
+		if i < 0 {
+			pending = append(pending, modKey, modValue)
+			continue
+		}
+
+		for k := next; k < i; k++ {
+			if !r.deleteLines(keyStarts[k], contentEnds[k]) {
+				return false
+			}
+		}
+		next = i + 1
+
+		// 新增的键插入到当前键之前
+		if len(pending) > 0 {
+			if !r.onlySpaceBefore(keyStarts[i]) {
+				return false
+			}
+			r.insertLines(r.lineStart(keyStarts[i]), pending, orig.Content[2*i].Column)
+			pending = nil
+		}
+
+		origKey, origValue := orig.Content[2*i], orig.Content[2*i+1]
+		if !NodesEqual(origKey, modKey) || !r.diff(origValue, modValue, boundaries[i]) {
+			r.replace(keyStarts[i], contentEnds[i],
+				r.renderInline(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{modKey, modValue}}, origKey.Column))
+		}
+		retained = i
+	}
+
+	for k := next; k < count; k++ {
+		if !r.deleteLines(keyStarts[k], contentEnds[k]) {
+			return false
+		}
+	}
+
+	// 剩余的新键追加到最后保留的键之后
+	if len(pending) > 0 {
+		r.insertLines(r.lineAfter(contentEnds[retained]), pending, orig.Content[2*retained].Column)
+	}
+
+	return true
+}
+
+// 逐项对比序列节点
+func (r *renderer) patchSequence(orig, mod *yaml.Node, limit int) bool {
+	count := len(orig.Content)
+
+	// 计算原始序列项的位置
+	dashStarts := make([]int, count)
+	contentEnds := make([]int, count)
+	boundaries := make([]int, count)
+	for i, item := range orig.Content {
+		dash := r.dashBefore(r.offset(item))
+		if dash < 0 {
+			return false
+		}
+		dashStarts[i] = dash
+	}
+	for i := range orig.Content {
+		boundaries[i] = limit
+		if i+1 < count {
+			boundaries[i] = r.lineStart(dashStarts[i+1])
+		}
+		contentEnds[i] = r.trimEnd(dashStarts[i], boundaries[i], isBlockScalar(orig.Content[i]))
+	}
+
+	// 先按内容完全相同对齐，再按name字段对齐
+	matched := alignNodes(orig.Content, mod.Content, NodesEqual)
+	matched = refineAlignment(orig.Content, mod.Content, matched, sameNamedItem)
+
+	dashColumn := orig.Column
+	var pending []*yaml.Node
+	next := 0
+	insertAt := r.lineStart(dashStarts[0])
+	for j, i := range matched {
+		if i < 0 {
+			pending = append(pending, mod.Content[j])
+			continue
+		}
+
+		for k := next; k < i; k++ {
+			if !r.deleteLines(dashStarts[k], contentEnds[k]) {
+				return false
+			}
+		}
+		next = i + 1
+
+		if len(pending) > 0 {
+			if !r.onlySpaceBefore(dashStarts[i]) {
+				return false
+			}
+			r.insertItems(insertAt, pending, dashColumn)
+			pending = nil
+		}
+
+		if !r.diff(orig.Content[i], mod.Content[j], boundaries[i]) {
+			return false
+		}
+		insertAt = r.lineAfter(contentEnds[i])
+	}
+
+	for k := next; k < count; k++ {
+		if !r.deleteLines(dashStarts[k], contentEnds[k]) {
+			return false
+		}
+	}
+
+	if len(pending) > 0 {
+		if next == 0 && !r.onlySpaceBefore(dashStarts[0]) {
+			return false
+		}
+		r.insertItems(insertAt, pending, dashColumn)
+	}
+
+	return true
+}
+
+// 计算两个节点列表的最长公共子序列对齐，返回mod中每项对应的orig下标
+func alignNodes(orig, mod []*yaml.Node, match func(a, b *yaml.Node) bool) []int {
+	n, m := len(orig), len(mod)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if match(orig[i], mod[j]) {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	result := make([]int, m)
+	for j := range result {
+		result[j] = -1
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		if match(orig[i], mod[j]) {
+			result[j] = i
+			i++
+			j++
+		} else if table[i+1][j] >= table[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return result
+}
+
+// 在已对齐的锚点之间继续对齐剩余的项，数量相同的剩余项按位置对齐
+func refineAlignment(orig, mod []*yaml.Node, matched []int, match func(a, b *yaml.Node) bool) []int {
+	origStart, modStart := 0, 0
+	for j := 0; j <= len(mod); j++ {
+		if j < len(mod) && matched[j] < 0 {
+			continue
+		}
+		origEnd := len(orig)
+		if j < len(mod) {
+			origEnd = matched[j]
+		}
+
+		sub := alignNodes(orig[origStart:origEnd], mod[modStart:j], match)
+		if origEnd-origStart == j-modStart {
+			// 数量相同时按位置对齐
+			for k := range sub {
+				sub[k] = k
+			}
+		}
+		for k, i := range sub {
+			if i >= 0 {
+				matched[modStart+k] = origStart + i
+			}
+		}
+
+		if j < len(mod) {
+			origStart, modStart = matched[j]+1, j+1
+		}
+	}
+
+	return matched
+}
+
+// 判断两个序列项是否为同名的映射（例如同名的环境变量或容器）
+func sameNamedItem(a, b *yaml.Node) bool {
+	nameA, okA := MappingString(a, "name")
+	nameB, okB := MappingString(b, "name")
+	return okA && okB && nameA == nameB
+}
+
+// 在指定位置插入新的键值对
+func (r *renderer) insertLines(at int, pairs []*yaml.Node, column int) {
+	text := r.render(&yaml.Node{Kind: yaml.MappingNode, Content: pairs})
+	r.insert(at, indentLines(text, column-1, true))
+}
+
+// 在指定位置插入新的序列项
+func (r *renderer) insertItems(at int, items []*yaml.Node, dashColumn int) {
+	text := r.render(&yaml.Node{Kind: yaml.SequenceNode, Content: items})
+	r.insert(at, indentLines(text, dashColumn-1, true))
+}
+
+func (r *renderer) insert(at int, text string) {
+	// 原文件末尾没有换行时，插入到末尾的内容也不以换行结束
+	if at == len(r.src) && at > 0 && r.src[at-1] != '\n' {
+		text = "\n" + strings.TrimSuffix(text, "\n")
+	}
+	r.edits = append(r.edits, edit{start: at, end: at, text: withNewline(text, r.newline)})
+}
+
+// 删除从start所在行到end所在行的整行内容
+func (r *renderer) deleteLines(start, end int) bool {
+	if !r.onlySpaceBefore(start) {
+		return false
+	}
+	r.replace(r.lineStart(start), r.lineAfter(end), "")
+	return true
+}
+
+func (r *renderer) replace(start, end int, text string) {
+	r.edits = append(r.edits, edit{start: start, end: end, text: withNewline(text, r.newline)})
+}
+
+// 渲染节点，首行从当前位置开始，后续行按列号缩进
+func (r *renderer) renderInline(node *yaml.Node, column int) string {
+	text := strings.TrimSuffix(r.render(node), "\n")
+	return indentLines(text, column-1, false)
+}
+
+func (r *renderer) render(node *yaml.Node) string {
+	text, err := encodeNode(node, r.indent)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.compact {
+		text = compactSequences(text)
+	}
+	return text
+}
+
+// 按指定的缩进宽度编码节点
+func encodeNode(node *yaml.Node, indent int) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(node); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 为文本的每一行添加缩进，空行除外
+func indentLines(text string, width int, firstLine bool) string {
+	if width <= 0 {
+		return text
+	}
+	prefix := strings.Repeat(" ", width)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" || (i == 0 && !firstLine) {
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// 节点在原始内容中的字节偏移
+func (r *renderer) offset(node *yaml.Node) int {
+	if node.Line < 1 || node.Line > len(r.lineStarts) {
+		return len(r.src)
+	}
+	pos := r.lineStarts[node.Line-1]
+	for col := 1; col < node.Column && pos < len(r.src) && r.src[pos] != '\n'; col++ {
+		_, size := utf8.DecodeRune(r.src[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// 偏移所在行的起始位置
+func (r *renderer) lineStart(pos int) int {
+	i := sort.Search(len(r.lineStarts), func(i int) bool { return r.lineStarts[i] > pos })
+	return r.lineStarts[i-1]
+}
+
+// 偏移所在行的换行符位置
+func (r *renderer) lineEnd(pos int) int {
+	if i := bytes.IndexByte(r.src[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(r.src)
+}
+
+// 偏移所在行的下一行起始位置
+func (r *renderer) lineAfter(pos int) int {
+	end := r.lineEnd(pos)
+	if end < len(r.src) {
+		return end + 1
+	}
+	return end
+}
+
+// 判断偏移之前是否只有空白
+func (r *renderer) onlySpaceBefore(pos int) bool {
+	return len(bytes.TrimLeft(r.src[r.lineStart(pos):pos], " \t")) == 0
+}
+
+// 判断该位置能否开始一个块集合：之前只能是空白或序列项标记
+func (r *renderer) blockAllowedAt(pos int) bool {
+	prefix := r.src[r.lineStart(pos):pos]
+	for _, field := range bytes.Fields(prefix) {
+		if !bytes.Equal(field, []byte("-")) {
+			return false
+		}
+	}
+	return true
+}
+
+// 查找序列项之前的"-"标记
+func (r *renderer) dashBefore(pos int) int {
+	for i := pos - 1; i >= 0; i-- {
+		switch r.src[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '-':
+			return i
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// 返回[start, limit)中最后一个有效内容的结束位置，跳过末尾的空行和整行注释
+func (r *renderer) trimEnd(start, limit int, keepComments bool) int {
+	end := limit
+	for end > start {
+		lineStart := r.lineStart(end - 1)
+		if lineStart < start {
+			lineStart = start
+		}
+		line := bytes.TrimRight(r.src[lineStart:end], " \t\r\n")
+		content := bytes.TrimLeft(line, " \t")
+		if len(content) > 0 && (content[0] != '#' || keepComments) {
+			return lineStart + len(line)
+		}
+		end = lineStart
+	}
+	return start
+}
+
+// 计算标量在原始内容中的结束位置
+func (r *renderer) scalarEnd(node *yaml.Node, limit int) int {
+	start := r.offset(node)
+
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < limit; i++ {
+			if r.src[i] == '\\' {
+				i++
+			} else if r.src[i] == '"' {
+				return i + 1
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < limit; i++ {
+			if r.src[i] == '\'' {
+				if i+1 < limit && r.src[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	case isBlockScalar(node):
+		return r.trimEnd(start, limit, true)
+	default:
+		line := r.src[start:r.lineEnd(start)]
+		if i := bytes.Index(line, []byte(" #")); i >= 0 {
+			line = line[:i]
+		}
+		line = bytes.TrimRight(line, " \t\r")
+		if string(line) == node.Value {
+			return start + len(line)
+		}
+	}
+
+	return r.trimEnd(start, limit, false)
+}
+
+func isBlockCollection(node *yaml.Node) bool {
+	return (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) &&
+		node.Style&yaml.FlowStyle == 0 && len(node.Content) > 0
+}
+
+func isBlockScalar(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+}
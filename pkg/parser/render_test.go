@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/diff"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 使用 go test ./pkg/parser -update 重新生成期望输出
+var update = flag.Bool("update", false, "重新生成testdata中的期望输出")
+
+// 模拟处理器的修改：为没有值的环境变量添加valueFrom，删除名为REMOVE_ME的环境变量
+func mutateEnv(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			mutateEnv(child)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value := node.Content[i+1]
+			if node.Content[i].Value == "env" && value.Kind == yaml.SequenceNode {
+				var kept []*yaml.Node
+				for _, envVar := range value.Content {
+					name, _ := MappingString(envVar, "name")
+					if name == "REMOVE_ME" {
+						continue
+					}
+					if MappingValue(envVar, "value") == nil && MappingValue(envVar, "valueFrom") == nil {
+						SetMappingValue(envVar, "valueFrom", NewMapping("configMapKeyRef",
+							NewMapping("name", "app-config", "key", name)))
+					}
+					kept = append(kept, envVar)
+				}
+				value.Content = kept
+				continue
+			}
+			mutateEnv(value)
+		}
+	}
+}
+
+// 对比渲染结果与testdata中的期望输出，覆盖注释、流式映射、CRLF、缺少末尾换行和四空格缩进
+func TestRenderGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "render", "*.input.yaml"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("找不到测试输入: %v", err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".input.yaml")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser := NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+			file := parser.ParseContent(input, data)
+			for _, document := range file.Documents {
+				if document.Node != nil {
+					mutateEnv(document.Node)
+				}
+			}
+
+			output, err := parser.EncodeFile(file)
+			if err != nil {
+				t.Fatalf("EncodeFile: %v", err)
+			}
+
+			golden := strings.TrimSuffix(input, ".input.yaml") + ".golden.yaml"
+			if *update {
+				if err := os.WriteFile(golden, output, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("读取期望输出失败（使用 -update 生成）: %v", err)
+			}
+			assertBytes(t, want, output)
+
+			// 重新解析输出，修改后的节点树应与输出一致，再次渲染不产生变化
+			again := parser.ParseContent(input, output)
+			rendered, err := parser.EncodeFile(again)
+			if err != nil {
+				t.Fatalf("再次编码失败: %v", err)
+			}
+			assertBytes(t, output, rendered)
+		})
+	}
+}
+
+// 渲染的内容沿用原文件的换行符和缩进
+func TestRenderStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		newline string
+		indent  int
+		compact bool
+	}{
+		{"LF两空格", "a:\n  b: 1\n", "\n", 2, false},
+		{"CRLF", "a:\r\n  b: 1\r\n", "\r\n", 2, false},
+		{"四空格", "a:\n    b: 1\n", "\n", 4, false},
+		{"序列中的映射", "items:\n  - name: x\n    spec:\n       c: 1\n", "\n", 3, false},
+		{"没有嵌套映射", "a: 1\nb: [1, 2]\n", "\n", defaultIndent, false},
+		{"紧凑序列", "a:\n  items:\n  - x\n", "\n", 2, true},
+		{"序列项中的紧凑序列", "- name: x\n  env:\n  - name: A\n", "\n", defaultIndent, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.input), &node); err != nil {
+				t.Fatal(err)
+			}
+			if got := detectNewline([]byte(tt.input)); got != tt.newline {
+				t.Errorf("detectNewline = %q, 期望 %q", got, tt.newline)
+			}
+			if got := detectIndent(rootOf(&node)); got != tt.indent {
+				t.Errorf("detectIndent = %d, 期望 %d", got, tt.indent)
+			}
+			if got := detectCompact(rootOf(&node)); got != tt.compact {
+				t.Errorf("detectCompact = %v, 期望 %v", got, tt.compact)
+			}
+		})
+	}
+}
+
+func TestCompactSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			"映射中的序列",
+			"envFrom:\n  - configMapRef:\n      name: app-config\n",
+			"envFrom:\n- configMapRef:\n    name: app-config\n",
+		},
+		{
+			"嵌套序列",
+			"a:\n  - b:\n      - x\n      - y\n    c: 1\nd: 2\n",
+			"a:\n- b:\n  - x\n  - y\n  c: 1\nd: 2\n",
+		},
+		{
+			"四空格",
+			"a:\n    - b:\n          - x\n",
+			"a:\n- b:\n  - x\n",
+		},
+		{
+			// 块标量的内容整体移动，其中形似键和序列的行不作为结构处理
+			"块标量",
+			"a:\n  - script: |\n      items:\n        - x\n",
+			"a:\n- script: |\n    items:\n      - x\n",
+		},
+		{"没有序列", "a:\n  b: 1\n", "a:\n  b: 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compactSequences(tt.input); got != tt.want {
+				t.Errorf("compactSequences =\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 插入的序列沿用原文件的紧凑风格，未修改的内容保持不变
+func TestRenderCompactInsert(t *testing.T) {
+	input := `containers:
+- name: web
+  env:
+  - name: OTHER
+    value: x
+`
+	parser := NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+	file := parser.ParseContent("app.yaml", []byte(input))
+	container := file.Documents[0].Root().Content[1].Content[0]
+	SetMappingValue(container, "envFrom", &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+		NewMapping("configMapRef", NewMapping("name", "app-config")),
+	}})
+
+	output, err := parser.EncodeFile(file)
+	if err != nil {
+		t.Fatalf("EncodeFile: %v", err)
+	}
+	assertBytes(t, []byte(input+`  envFrom:
+  - configMapRef:
+      name: app-config
+`), output)
+}
+
+// 逐字节比较，不一致时输出首个差异位置和统一差异
+func assertBytes(t *testing.T, want, got []byte) {
+	t.Helper()
+	if bytes.Equal(want, got) {
+		return
+	}
+
+	offset := 0
+	for offset < len(want) && offset < len(got) && want[offset] == got[offset] {
+		offset++
+	}
+	context := func(data []byte) string {
+		start, end := offset-20, offset+20
+		if start < 0 {
+			start = 0
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+		if start > end {
+			start = end
+		}
+		return string(data[start:end])
+	}
+	t.Errorf("第%d个字节开始不一致\n期望: %q\n实际: %q\n%s", offset, context(want), context(got),
+		diff.Unified("want", "got", want, got, 3, false))
+}
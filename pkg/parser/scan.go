@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 目录扫描选项
+type ScanOptions struct {
+	// 需要跳过的目录，例如输出目录
+	ExcludeDirs []string
+	// 只扫描匹配这些glob模式的文件，为空时扫描所有YAML文件
+	Include []string
+	// 跳过匹配这些glob模式的文件和目录
+	Exclude []string
+}
+
+// 忽略文件中的一条规则
+type ignoreRule struct {
+	// 规则所在目录相对于扫描目录的路径，根目录为空
+	base string
+	// 匹配模式
+	pattern string
+	// 是否为取反规则（!开头）
+	negate bool
+	// 是否只匹配目录（/结尾）
+	dirOnly bool
+}
+
+// 扫描目录中的所有YAML文件，跳过输出目录、默认忽略的目录、
+// .gitignore和.k8sconfigignore中忽略的路径以及--exclude匹配的路径
+func (p *YAMLParser) ScanDirectory(directory string, options ScanOptions) ([]string, error) {
+	var yamlFiles []string
+
+	excludeDirs := make(map[string]bool)
+	for _, dir := range options.ExcludeDirs {
+		excludeDirs[AbsPath(dir)] = true
+	}
+	// 输出目录与输入目录相同时不能跳过
+	delete(excludeDirs, AbsPath(directory))
+
+	// 每个目录生效的忽略规则，包含上级目录的规则
+	rules := make(map[string][]ignoreRule)
+
+	// 递归遍历目录
+	err := filepath.Walk(directory, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(directory, filePath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			relPath = ""
+		}
+		parentRules := rules[path.Dir(relPath)]
+		if relPath == "" {
+			parentRules = nil
+		}
+
+		if info.IsDir() {
+			if relPath != "" {
+				if isIgnoredDirName(info.Name()) || excludeDirs[AbsPath(filePath)] ||
+					ignored(parentRules, relPath, true) || matchAny(options.Exclude, relPath) {
+					return filepath.SkipDir
+				}
+			}
+
+			// 加载本目录的忽略文件，规则对所有下级路径生效
+			dirRules := append([]ignoreRule(nil), parentRules...)
+			for _, name := range []string{utils.GitIgnoreFile, utils.K8sConfigIgnoreFile} {
+				loaded, err := loadIgnoreFile(filepath.Join(filePath, name), relPath)
+				if err != nil {
+					return err
+				}
+				dirRules = append(dirRules, loaded...)
+			}
+			key := relPath
+			if key == "" {
+				key = "."
+			}
+			rules[key] = dirRules
+			return nil
+		}
+
+		// 检查文件扩展名，没有扩展名的Kustomization文件同样读取
+		ext := strings.ToLower(filepath.Ext(filePath))
+		if ext != utils.YamlExt && ext != utils.YmlExt && info.Name() != utils.KustomizationFile {
+			return nil
+		}
+
+		if ignored(parentRules, relPath, false) || matchAny(options.Exclude, relPath) {
+			return nil
+		}
+		if len(options.Include) > 0 && !matchAny(options.Include, relPath) {
+			return nil
+		}
+
+		yamlFiles = append(yamlFiles, filePath)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.Report.TotalFiles = len(yamlFiles)
+	return yamlFiles, nil
+}
+
+// 判断是否为默认忽略的目录
+func isIgnoredDirName(name string) bool {
+	for _, ignoredName := range utils.DefaultIgnoredDirs {
+		if name == ignoredName {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取规范化的绝对路径，用于比较路径；失败时返回规范化的原路径
+func AbsPath(filePath string) string {
+	if abs, err := filepath.Abs(filePath); err == nil {
+		return abs
+	}
+	return filepath.Clean(filePath)
+}
+
+// 读取忽略文件，文件不存在时返回空规则
+func loadIgnoreFile(filePath, base string) ([]ignoreRule, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// 按gitignore的语义判断路径是否被忽略，后出现的规则优先
+func ignored(rules []ignoreRule, relPath string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		// 规则只对所在目录下的路径生效
+		target := relPath
+		if rule.base != "" {
+			if !strings.HasPrefix(relPath, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(relPath, rule.base+"/")
+		}
+
+		if matchPattern(rule.pattern, target) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// 判断路径是否匹配任意一个模式
+func matchAny(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// 匹配gitignore风格的模式：不含/的模式匹配任意层级的名称，
+// 含/的模式相对于根目录匹配，**匹配任意层级的目录
+func matchPattern(pattern, relPath string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if !strings.Contains(strings.TrimPrefix(pattern, "/"), "/") && !strings.HasPrefix(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// 逐段匹配路径
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// **匹配零个或多个目录
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], segments[0]); err != nil || !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+
+	return len(segments) == 0
+}
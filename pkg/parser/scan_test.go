@@ -0,0 +1,213 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		relPath string
+		want    bool
+	}{
+		// 不含/的模式匹配任意层级的名称
+		{"*.yml", "a.yml", true},
+		{"*.yml", "apps/web/a.yml", true},
+		{"charts", "apps/charts", true},
+		{"*.yml", "a.yaml", false},
+		// 含/的模式相对于根目录匹配，*不跨越目录
+		{"apps/*.yaml", "apps/web.yaml", true},
+		{"apps/*.yaml", "apps/web/deploy.yaml", false},
+		{"apps/*.yaml", "other/apps/web.yaml", false},
+		{"/web.yaml", "web.yaml", true},
+		{"/web.yaml", "apps/web.yaml", false},
+		{"./apps/*.yaml", "apps/web.yaml", true},
+		// **匹配零个或多个目录
+		{"**/charts", "charts", true},
+		{"**/charts", "a/b/charts", true},
+		{"apps/**/*.yaml", "apps/web.yaml", true},
+		{"apps/**/*.yaml", "apps/a/b/web.yaml", true},
+		{"apps/**/*.yaml", "base/web.yaml", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		{"apps/**", "apps/web/deploy.yaml", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.relPath); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, 期望 %v", tt.pattern, tt.relPath, got, tt.want)
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []ignoreRule
+		relPath string
+		isDir   bool
+		want    bool
+	}{
+		{"匹配", []ignoreRule{{pattern: "*.secret.yaml"}}, "apps/db.secret.yaml", false, true},
+		{"!重新包含", []ignoreRule{{pattern: "*.yaml"}, {pattern: "keep.yaml", negate: true}}, "apps/keep.yaml", false, false},
+		{"!不影响其他文件", []ignoreRule{{pattern: "*.yaml"}, {pattern: "keep.yaml", negate: true}}, "apps/drop.yaml", false, true},
+		// 后出现的规则优先
+		{"取反后再次忽略", []ignoreRule{{pattern: "*.yaml"}, {pattern: "keep.yaml", negate: true}, {pattern: "apps/keep.yaml"}}, "apps/keep.yaml", false, true},
+		// /结尾的规则只匹配目录
+		{"目录规则匹配目录", []ignoreRule{{pattern: "build", dirOnly: true}}, "apps/build", true, true},
+		{"目录规则不匹配文件", []ignoreRule{{pattern: "build", dirOnly: true}}, "apps/build", false, false},
+		// 下级目录中的忽略文件只对该目录下的路径生效，含/的模式相对于该目录
+		{"下级规则在目录内生效", []ignoreRule{{base: "apps", pattern: "*.tmp.yaml"}}, "apps/web/a.tmp.yaml", false, true},
+		{"下级规则在目录外不生效", []ignoreRule{{base: "apps", pattern: "*.tmp.yaml"}}, "base/a.tmp.yaml", false, false},
+		{"下级规则不匹配同名前缀的目录", []ignoreRule{{base: "apps", pattern: "*.yaml"}}, "apps2/a.yaml", false, false},
+		{"下级规则的锚定模式", []ignoreRule{{base: "apps", pattern: "/web.yaml"}}, "apps/web.yaml", false, true},
+		{"下级规则的锚定模式不匹配更深的路径", []ignoreRule{{base: "apps", pattern: "/web.yaml"}}, "apps/sub/web.yaml", false, false},
+		{"下级规则可以重新包含上级忽略的文件", []ignoreRule{{pattern: "*.yaml"}, {base: "apps", pattern: "web.yaml", negate: true}}, "apps/web.yaml", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ignored(tt.rules, tt.relPath, tt.isDir); got != tt.want {
+				t.Errorf("ignored(%s) = %v, 期望 %v", tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), utils.K8sConfigIgnoreFile)
+	content := "# 注释\n\n*.tmp.yaml  \n!keep.tmp.yaml\nbuild/\n!\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := loadIgnoreFile(filePath, "apps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ignoreRule{
+		{base: "apps", pattern: "*.tmp.yaml"},
+		{base: "apps", pattern: "keep.tmp.yaml", negate: true},
+		{base: "apps", pattern: "build", dirOnly: true},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, 期望 %+v", rules, want)
+	}
+
+	if rules, err := loadIgnoreFile(filepath.Join(t.TempDir(), "missing"), ""); err != nil || rules != nil {
+		t.Errorf("不存在的忽略文件 = %v, %v", rules, err)
+	}
+}
+
+func TestScanDirectory(t *testing.T) {
+	files := map[string]string{
+		".gitignore":                     "*.generated.yaml\ntmp*/\n",
+		"web.yaml":                       "",
+		"web.generated.yaml":             "",
+		"README.md":                      "",
+		"Kustomization":                  "",
+		"tmp/out.yaml":                   "",
+		"tmp-web.yaml":                   "",
+		"apps/api.yml":                   "",
+		"apps/.k8sconfigignore":          "*.local.yaml\n!keep.generated.yaml\n",
+		"apps/dev.local.yaml":            "",
+		"apps/keep.generated.yaml":       "",
+		"apps/charts/chart.yaml":         "",
+		"base/dev.local.yaml":            "",
+		"vendor/lib.yaml":                "",
+		"node_modules/pkg/deploy.yaml":   "",
+		"processed/web.yaml":             "",
+		"apps/web/deployment.yaml":       "",
+		"apps/web/values.generated.yaml": "",
+	}
+	root := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 忽略文件和默认忽略的目录之后的文件
+	all := []string{
+		"Kustomization",
+		"apps/api.yml",
+		"apps/charts/chart.yaml",
+		"apps/keep.generated.yaml",
+		"apps/web/deployment.yaml",
+		"base/dev.local.yaml",
+		"tmp-web.yaml",
+		"web.yaml",
+	}
+
+	tests := []struct {
+		name    string
+		options ScanOptions
+		want    []string
+	}{
+		{"忽略文件", ScanOptions{ExcludeDirs: []string{filepath.Join(root, "processed")}}, all},
+		{
+			"include只筛选文件",
+			ScanOptions{ExcludeDirs: []string{filepath.Join(root, "processed")}, Include: []string{"apps/**/*.yaml"}},
+			[]string{"apps/charts/chart.yaml", "apps/keep.generated.yaml", "apps/web/deployment.yaml"},
+		},
+		{
+			"exclude跳过目录",
+			ScanOptions{ExcludeDirs: []string{filepath.Join(root, "processed")}, Exclude: []string{"**/charts"}},
+			[]string{"Kustomization", "apps/api.yml", "apps/keep.generated.yaml", "apps/web/deployment.yaml", "base/dev.local.yaml", "tmp-web.yaml", "web.yaml"},
+		},
+		{
+			// 同时匹配include和exclude的文件被跳过
+			"exclude优先于include",
+			ScanOptions{
+				ExcludeDirs: []string{filepath.Join(root, "processed")},
+				Include:     []string{"apps/**/*.yaml", "web.yaml"},
+				Exclude:     []string{"apps/web", "*.generated.yaml"},
+			},
+			[]string{"apps/charts/chart.yaml", "web.yaml"},
+		},
+		{
+			"未排除输出目录",
+			ScanOptions{},
+			append(append([]string(nil), all...), "processed/web.yaml"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+			scanned, err := parser.ScanDirectory(root, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, filePath := range scanned {
+				relPath, err := filepath.Rel(root, filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(relPath))
+			}
+			want := append([]string(nil), tt.want...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ScanDirectory() = %v\n期望 %v", got, want)
+			}
+			if parser.Report.TotalFiles != len(want) {
+				t.Errorf("TotalFiles = %d, 期望 %d", parser.Report.TotalFiles, len(want))
+			}
+		})
+	}
+}
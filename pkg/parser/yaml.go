@@ -2,10 +2,8 @@ package parser
 
 import (
 	"bytes"
-	"io"
+	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/k8sconfig-processor/pkg/utils"
 	"gopkg.in/yaml.v3"
@@ -27,93 +25,117 @@ func NewYAMLParser(configCache *utils.ConfigCache, report *utils.ProcessReport)
 	}
 }
 
-// 扫描目录中的所有YAML文件
-func (p *YAMLParser) ScanDirectory(directory string) ([]string, error) {
-	var yamlFiles []string
-
-	// 递归遍历目录
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// 跳过目录
-		if info.IsDir() {
-			return nil
-		}
-
-		// 检查文件扩展名
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext == utils.YamlExt || ext == utils.YmlExt {
-			yamlFiles = append(yamlFiles, path)
-		}
-
-		return nil
-	})
-
+// 解析单个YAML文件中的所有文档
+func (p *YAMLParser) ParseFile(filePath string) (*File, error) {
+	// 读取文件内容
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	p.Report.TotalFiles = len(yamlFiles)
-	return yamlFiles, nil
+	return p.ParseContent(filePath, data), nil
 }
 
-// 解析单个YAML文件中的所有文档
-func (p *YAMLParser) ParseFile(filePath string) ([]utils.KubeResource, error) {
-	// 读取文件内容
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+// 解析已读取的文件内容，无法解析的文档记录错误并按原始内容保留
+func (p *YAMLParser) ParseContent(filePath string, data []byte) *File {
+	file := &File{
+		Path:    filePath,
+		Content: data,
 	}
 
-	// 解析文件中的多个YAML文档
-	var resources []utils.KubeResource
-	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// 按分隔符拆分文件中的多个YAML文档，逐个解析为节点树
+	line := 1
+	for _, raw := range splitDocuments(data) {
+		document := &Document{
+			Path:      filePath,
+			Raw:       raw,
+			StartLine: line,
+		}
+		line += bytes.Count(raw, []byte("\n"))
+		file.Documents = append(file.Documents, document)
 
-	for {
-		var resource utils.KubeResource
-		err := decoder.Decode(&resource)
-		if err == io.EOF {
-			break
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err != nil {
+			document.Err = err
+			p.reportParseError(document, err)
+			continue
 		}
-		if err != nil {
-			p.Report.Errors = append(p.Report.Errors,
-				"解析文件失败: "+filePath+": "+err.Error())
+
+		// 空文档（仅包含注释或分隔符）原样保留
+		if node.Kind == 0 {
 			continue
 		}
 
-		// 确保命名空间字段有值
-		if resource.Metadata.Namespace == "" {
-			resource.Metadata.Namespace = utils.DefaultNamespace
+		document.Node = &node
+		document.original = CopyNode(&node)
+
+		if err := node.Decode(&document.Resource); err != nil {
+			document.Err = err
+			p.reportParseError(document, err)
+			document.Node = nil
+			continue
 		}
 
-		resources = append(resources, resource)
+		// 确保命名空间字段有值
+		if document.Resource.Metadata.Namespace == "" {
+			document.Resource.Metadata.Namespace = utils.DefaultNamespace
+		}
 	}
 
-	return resources, nil
+	return file
+}
+
+// 记录文档解析错误
+func (p *YAMLParser) reportParseError(document *Document, err error) {
+	p.Report.Add(utils.Finding{
+		Severity:   utils.SeverityError,
+		Code:       utils.CodeParseError,
+		File:       document.Path,
+		Line:       document.StartLine,
+		Message:    "解析文件失败: " + err.Error(),
+		Suggestion: "修正YAML语法或资源字段的类型",
+	})
 }
 
-// 将资源编码为YAML
-func (p *YAMLParser) EncodeToYAML(resources []utils.KubeResource) ([]byte, error) {
+// 将文件编码为YAML，只有被修改的片段与原文件不同
+func (p *YAMLParser) EncodeFile(file *File) ([]byte, error) {
 	var resultBuf bytes.Buffer
 
-	// 遍历所有资源
-	for i, resource := range resources {
-		// 使用yaml.Marshal来编码资源
-		yamlData, err := yaml.Marshal(resource)
+	// 按原始顺序渲染所有文档
+	for _, document := range file.Documents {
+		data, err := document.Render()
 		if err != nil {
 			return nil, err
 		}
+		resultBuf.Write(data)
+	}
 
-		// 将编码后的数据写入结果缓冲区
-		resultBuf.Write(yamlData)
+	result := resultBuf.Bytes()
+	if err := verifyOutput(file, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
 
-		// 除了最后一个资源外，在每个资源后添加分隔符
-		if i < len(resources)-1 {
-			resultBuf.WriteString("---\n")
+// 校验输出保留了所有文档：文档数量不变，未修改的文档（包括无法解析的文档）
+// 在原来的位置逐字节保留，否则拒绝输出，避免删除或截断资源
+func verifyOutput(file *File, output []byte) error {
+	chunks := splitDocuments(output)
+	if len(chunks) != len(file.Documents) {
+		return fmt.Errorf("输出的文档数量(%d)与原文件(%d)不一致，拒绝写入: %s",
+			len(chunks), len(file.Documents), file.Path)
+	}
+
+	for i, document := range file.Documents {
+		if document.Modified() {
+			continue
+		}
+		if !bytes.Equal(chunks[i], document.Raw) {
+			return fmt.Errorf("第%d行开始的文档在输出中发生变化，拒绝写入: %s",
+				document.StartLine, file.Path)
 		}
 	}
 
-	return resultBuf.Bytes(), nil
+	return nil
 }
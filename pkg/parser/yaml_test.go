@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const brokenMiddleInput = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: first
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: LOG_LEVEL
+---
+# 无法解析：未闭合的流式序列
+kind: ConfigMap
+data: [unclosed
+  key:   value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: last
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: PORT
+`
+
+// 无法解析的文档在输出中的原位置逐字节保留，其余文档照常修改
+func TestEncodeFileKeepsBrokenDocument(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"YAML语法错误", brokenMiddleInput},
+		{"字段类型错误", strings.Replace(brokenMiddleInput, "data: [unclosed\n  key:   value\n", "metadata: [not, a, map]\n", 1)},
+		{"CRLF", strings.ReplaceAll(brokenMiddleInput, "\n", "\r\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := utils.NewProcessReport()
+			parser := NewYAMLParser(utils.NewConfigCache(), report)
+			file := parser.ParseContent("broken.yaml", []byte(tt.input))
+
+			if len(file.Documents) != 3 {
+				t.Fatalf("解析出%d个文档, 期望3个", len(file.Documents))
+			}
+			broken := file.Documents[1]
+			if !file.HasErrors() || broken.Err == nil {
+				t.Fatal("中间的文档应解析失败")
+			}
+			if len(report.Findings) != 1 || report.Findings[0].Code != utils.CodeParseError ||
+				report.Findings[0].Line != broken.StartLine {
+				t.Errorf("解析错误未按文档位置记入报告: %+v", report.Findings)
+			}
+
+			for _, document := range file.Documents {
+				if document.Node != nil {
+					mutateEnv(document.Node)
+				}
+			}
+			output, err := parser.EncodeFile(file)
+			if err != nil {
+				t.Fatalf("EncodeFile: %v", err)
+			}
+
+			chunks := splitDocuments(output)
+			if len(chunks) != 3 {
+				t.Fatalf("输出中有%d个文档, 期望3个", len(chunks))
+			}
+			assertBytes(t, broken.Raw, chunks[1])
+
+			// 无法解析的文档紧接在修改后的第一个文档之后
+			offset := len(chunks[0])
+			if !bytes.HasPrefix(output[offset:], broken.Raw) {
+				t.Error("无法解析的文档位置发生变化")
+			}
+			for i, chunk := range chunks {
+				if i != 1 && !bytes.Contains(chunk, []byte("app-config")) {
+					t.Errorf("第%d个文档没有被修改:\n%s", i, chunk)
+				}
+			}
+		})
+	}
+}
+
+// 输出丢失、增加或改动了未修改的文档时拒绝写入
+func TestVerifyOutput(t *testing.T) {
+	input := []byte("a: 1\n---\nb: [broken\n---\nc: 3\n")
+	parser := NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+
+	tests := []struct {
+		name    string
+		output  string
+		wantErr bool
+	}{
+		{"完全一致", "a: 1\n---\nb: [broken\n---\nc: 3\n", false},
+		{"丢失中间的文档", "a: 1\n---\nc: 3\n", true},
+		{"丢失最后的文档", "a: 1\n---\nb: [broken\n", true},
+		{"增加文档", "a: 1\n---\nb: [broken\n---\nc: 3\n---\nd: 4\n", true},
+		{"截断无法解析的文档", "a: 1\n---\nb: [bro\n---\nc: 3\n", true},
+		{"改动未修改的文档", "a: 1\n---\nb: [broken\n---\nc: 4\n", true},
+		{"去掉末尾换行", "a: 1\n---\nb: [broken\n---\nc: 3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := parser.ParseContent("verify.yaml", input)
+			err := verifyOutput(file, []byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyOutput() 错误 = %v, 期望错误 = %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 被修改的文档允许与原文不同
+	file := parser.ParseContent("verify.yaml", input)
+	SetMappingValue(file.Documents[2].Node.Content[0], "c", NewMapping("d", "4"))
+	if err := verifyOutput(file, []byte("a: 1\n---\nb: [broken\n---\nc:\n  d: \"4\"\n")); err != nil {
+		t.Errorf("被修改的文档不应被拒绝: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// pod规格引用的配置对象
+type configRef struct {
+	kind string
+	name string
+}
+
+// 在pod模板的注解中写入引用的配置对象的哈希，配置内容变化时触发滚动更新；
+// 本工具写入的注解记录在所有权注解中，其中不再引用的配置对象的注解被删除，其他checksum/注解保持不变。
+// 只处理带有模板的工作负载（pod规格路径以template.spec结尾）
+func (p *WorkloadProcessor) annotateChecksums(document *parser.Document, paths [][]string) bool {
+	namespace := document.Resource.Metadata.Namespace
+	modified := false
+
+	for _, path := range paths {
+		if len(path) < 2 || path[len(path)-1] != "spec" {
+			continue
+		}
+		template, err := findPodSpec(document.Root(), path[:len(path)-1])
+		if err != nil || template == nil {
+			continue
+		}
+		spec := parser.MappingValue(template, "spec")
+		owned := ownedChecksums(parser.LookupPath(template, "metadata", "annotations"))
+
+		// 名称相同的ConfigMap和Secret共用一个注解
+		checksums := make(map[string]string)
+		// 仍被引用但无法计算哈希的配置对象，已有的注解保持不变
+		kept := make(map[string]bool)
+		var names []string
+		for _, ref := range referencedConfigs(spec) {
+			// 只读取了键的Secret的哈希不随值变化，写入会造成内容未变的假象
+			if ref.kind == utils.SecretKind && p.ConfigCache.IsKeysOnly(namespace, ref.name) {
+				kept[ref.name] = true
+				p.Report.Add(utils.Finding{
+					Severity:   utils.SeverityWarning,
+					Code:       utils.CodeChecksumSkipped,
+					File:       document.Path,
+					Line:       document.Line(template),
+					Namespace:  namespace,
+					Workload:   document.Resource.Metadata.Name,
+					Message:    fmt.Sprintf("Secret %s/%s 只读取了键，无法计算注解 %s", namespace, ref.name, checksumAnnotationKey(ref.name)),
+					Suggestion: "不使用--secret-keys-only，或从文件提供该Secret",
+				})
+				continue
+			}
+			// 缓存中不存在的配置对象可能由其他工具管理
+			checksum, exists := configChecksum(p.ConfigCache, ref.kind, namespace, ref.name)
+			if !exists {
+				kept[ref.name] = true
+				continue
+			}
+			if previous, seen := checksums[ref.name]; seen {
+				checksum = combineChecksums(previous, checksum)
+			} else {
+				names = append(names, ref.name)
+			}
+			checksums[ref.name] = checksum
+		}
+
+		// 保留的注解和写入后本工具拥有的注解
+		wanted := make(map[string]bool)
+		owns := make(map[string]bool)
+		for name := range kept {
+			key := checksumAnnotationKey(name)
+			wanted[key] = true
+			owns[key] = owned[key]
+		}
+		for _, name := range names {
+			if kept[name] {
+				continue
+			}
+			key := checksumAnnotationKey(name)
+			wanted[key] = true
+			owns[key] = true
+
+			annotations := parser.LookupPath(template, "metadata", "annotations")
+			if current, _ := parser.MappingString(annotations, key); current == checksums[name] {
+				continue
+			}
+			parser.SetMappingValue(templateAnnotations(template), key, parser.NewScalar(checksums[name]))
+			modified = true
+
+			p.Report.Add(utils.Finding{
+				Severity:  utils.SeverityInfo,
+				Code:      utils.CodeChecksumUpdated,
+				File:      document.Path,
+				Line:      document.Line(template),
+				Namespace: namespace,
+				Workload:  document.Resource.Metadata.Name,
+				Message:   fmt.Sprintf("更新注解 %s (资源: %s/%s)", key, namespace, document.Resource.Metadata.Name),
+			})
+		}
+
+		removed, changed := pruneChecksums(template, owned, wanted, owns)
+		modified = modified || changed
+		for _, key := range removed {
+			p.Report.Add(utils.Finding{
+				Severity:  utils.SeverityInfo,
+				Code:      utils.CodeChecksumRemoved,
+				File:      document.Path,
+				Line:      document.Line(template),
+				Namespace: namespace,
+				Workload:  document.Resource.Metadata.Name,
+				Message:   fmt.Sprintf("删除注解 %s (资源: %s/%s)", key, namespace, document.Resource.Metadata.Name),
+			})
+		}
+	}
+
+	return modified
+}
+
+// 所有权注解中列出的注解键
+func ownedChecksums(annotations *yaml.Node) map[string]bool {
+	owned := make(map[string]bool)
+	value, _ := parser.MappingString(annotations, utils.ChecksumOwnerAnnotation)
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); strings.HasPrefix(key, utils.ChecksumAnnotationPrefix) {
+			owned[key] = true
+		}
+	}
+	return owned
+}
+
+// 获取模板的注解，不存在时创建
+func templateAnnotations(template *yaml.Node) *yaml.Node {
+	metadata := parser.MappingValue(template, "metadata")
+	if metadata == nil || metadata.Kind != yaml.MappingNode {
+		metadata = parser.NewMapping()
+		parser.SetMappingValue(template, "metadata", metadata)
+	}
+	annotations := parser.MappingValue(metadata, "annotations")
+	if annotations == nil || annotations.Kind != yaml.MappingNode {
+		annotations = parser.NewMapping()
+		parser.SetMappingValue(metadata, "annotations", annotations)
+	}
+	return annotations
+}
+
+// 配置对象名称对应的注解键。前缀之后的名称部分最长63个字符，
+// 超出时截断并附加完整名称哈希的前10位，不同的长名称不会共用一个注解
+func checksumAnnotationKey(name string) string {
+	if len(name) <= utils.MaxAnnotationNameLength {
+		return utils.ChecksumAnnotationPrefix + name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:10]
+	return utils.ChecksumAnnotationPrefix + name[:utils.MaxAnnotationNameLength-len(suffix)-1] + "-" + suffix
+}
+
+// 删除owned中不在wanted中的注解，并将所有权注解更新为owns中的键；注解或元数据因此为空时一并删除，
+// 返回删除的键和模板是否修改
+func pruneChecksums(template *yaml.Node, owned, wanted, owns map[string]bool) ([]string, bool) {
+	metadata := parser.MappingValue(template, "metadata")
+	annotations := parser.MappingValue(metadata, "annotations")
+
+	var removed []string
+	if annotations != nil && annotations.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(annotations.Content); i += 2 {
+			key := annotations.Content[i].Value
+			if owned[key] && !wanted[key] {
+				removed = append(removed, key)
+			}
+		}
+	}
+	for _, key := range removed {
+		parser.DeleteMappingValue(annotations, key)
+	}
+
+	var keys []string
+	for key, owner := range owns {
+		if owner {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	marker := strings.Join(keys, ",")
+
+	current, _ := parser.MappingString(annotations, utils.ChecksumOwnerAnnotation)
+	changed := len(removed) > 0 || current != marker
+	switch {
+	case current == marker:
+	case marker == "":
+		parser.DeleteMappingValue(annotations, utils.ChecksumOwnerAnnotation)
+	default:
+		annotations = templateAnnotations(template)
+		metadata = parser.MappingValue(template, "metadata")
+		parser.SetMappingValue(annotations, utils.ChecksumOwnerAnnotation, parser.NewScalar(marker))
+	}
+
+	if changed && annotations != nil && len(annotations.Content) == 0 {
+		parser.DeleteMappingValue(metadata, "annotations")
+		if len(metadata.Content) == 0 {
+			parser.DeleteMappingValue(template, "metadata")
+		}
+	}
+	return removed, changed
+}
+
+// 收集pod规格中通过env、envFrom和卷引用的配置对象，按类型和名称排序并去重
+func referencedConfigs(spec *yaml.Node) []configRef {
+	seen := make(map[configRef]bool)
+	var refs []configRef
+	forEachConfigReference(spec, func(kind string, node *yaml.Node, nameField string) {
+		name, _ := parser.MappingString(node, nameField)
+		ref := configRef{kind: kind, name: name}
+		if name != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	})
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].name != refs[j].name {
+			return refs[i].name < refs[j].name
+		}
+		return refs[i].kind < refs[j].kind
+	})
+	return refs
+}
+
+// 遍历pod规格中引用配置对象的节点：env的valueFrom、envFrom、configMap/secret卷和projected卷，
+// nameField为节点中保存对象名称的字段
+func forEachConfigReference(spec *yaml.Node, add func(kind string, node *yaml.Node, nameField string)) {
+	forEachContainer(spec, func(listName string, container *yaml.Node) {
+		if env := parser.MappingValue(container, "env"); env != nil && env.Kind == yaml.SequenceNode {
+			for _, envVar := range env.Content {
+				if ref := parser.LookupPath(envVar, "valueFrom", "configMapKeyRef"); ref != nil {
+					add(utils.ConfigMapKind, ref, "name")
+				}
+				if ref := parser.LookupPath(envVar, "valueFrom", "secretKeyRef"); ref != nil {
+					add(utils.SecretKind, ref, "name")
+				}
+			}
+		}
+		if envFrom := parser.MappingValue(container, "envFrom"); envFrom != nil && envFrom.Kind == yaml.SequenceNode {
+			for _, item := range envFrom.Content {
+				if ref := parser.MappingValue(item, "configMapRef"); ref != nil {
+					add(utils.ConfigMapKind, ref, "name")
+				}
+				if ref := parser.MappingValue(item, "secretRef"); ref != nil {
+					add(utils.SecretKind, ref, "name")
+				}
+			}
+		}
+	})
+
+	if volumes := parser.MappingValue(spec, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+		for _, volume := range volumes.Content {
+			if source := parser.MappingValue(volume, "configMap"); source != nil {
+				add(utils.ConfigMapKind, source, "name")
+			}
+			if source := parser.MappingValue(volume, "secret"); source != nil {
+				add(utils.SecretKind, source, "secretName")
+			}
+			sources := parser.LookupPath(volume, "projected", "sources")
+			if sources == nil || sources.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, projection := range sources.Content {
+				if source := parser.MappingValue(projection, "configMap"); source != nil {
+					add(utils.ConfigMapKind, source, "name")
+				}
+				if source := parser.MappingValue(projection, "secret"); source != nil {
+					add(utils.SecretKind, source, "name")
+				}
+			}
+		}
+	}
+}
+
+// 计算缓存中配置对象数据的SHA-256，与键的顺序无关；对象不存在时返回false
+func configChecksum(cache *utils.ConfigCache, kind, namespace, name string) (string, bool) {
+	data, exists := cacheObjects(cache, kind)[namespace][name]
+	if !exists {
+		return "", false
+	}
+
+	hash := sha256.New()
+	writeEntries := func(field string, entries map[string]string) {
+		for _, key := range sortedKeys(entries) {
+			fmt.Fprintf(hash, "%s\x00%s\x00%d\x00%s\x00", field, key, len(entries[key]), entries[key])
+		}
+	}
+	writeEntries(utils.DataField, data)
+	if kind == utils.ConfigMapKind {
+		writeEntries(utils.BinaryDataField, cache.BinaryData[namespace][name])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
+
+// 合并同名ConfigMap和Secret的哈希
+func combineChecksums(first, second string) string {
+	sum := sha256.Sum256([]byte(first + second))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,201 @@
+package processor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// 注解键中名称部分的格式（Kubernetes的限定名称）
+var qualifiedName = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+
+func TestChecksumAnnotationKey(t *testing.T) {
+	name63 := strings.Repeat("a", 63)
+	long := strings.Repeat("config.", 20) + "x"
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app-config", "checksum/app-config"},
+		{name63, "checksum/" + name63},
+		{name63 + "b", ""},
+		{long, ""},
+	}
+
+	for _, tt := range tests {
+		key := checksumAnnotationKey(tt.name)
+		if tt.want != "" && key != tt.want {
+			t.Errorf("checksumAnnotationKey(%q) = %q, 期望 %q", tt.name, key, tt.want)
+		}
+		part := strings.TrimPrefix(key, utils.ChecksumAnnotationPrefix)
+		if len(part) > utils.MaxAnnotationNameLength || !qualifiedName.MatchString(part) {
+			t.Errorf("checksumAnnotationKey(%q) = %q 不是有效的注解键", tt.name, key)
+		}
+		if checksumAnnotationKey(tt.name) != key {
+			t.Errorf("checksumAnnotationKey(%q) 的结果不稳定", tt.name)
+		}
+	}
+
+	// 前缀相同的长名称对应不同的键
+	if checksumAnnotationKey(name63+"b") == checksumAnnotationKey(name63+"c") {
+		t.Error("不同的长名称不应共用一个注解")
+	}
+}
+
+const checksumWorkload = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+      annotations:
+        k8sconfig-processor/checksums: checksum/app-config,checksum/removed-config
+        checksum/removed-config: 0123
+        checksum/app-config: stale
+        checksum/config: helm
+        checksum/missing-config: external
+        team: payments
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: app-config
+            - configMapRef:
+                name: missing-config
+            - secretRef:
+                name: db-secret
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: idle
+spec:
+  template:
+    metadata:
+      annotations:
+        k8sconfig-processor/checksums: checksum/app-config
+        checksum/app-config: stale
+    spec:
+      containers:
+        - name: idle
+          image: idle
+`
+
+// 更新引用的配置的注解，只删除本工具写入且不再引用的配置的注解；其他工具写入的注解和缓存中不存在的配置的注解保持不变，
+// 只有键的Secret保留已有注解并报告警告
+func TestAnnotateChecksums(t *testing.T) {
+	tests := []struct {
+		name      string
+		keysOnly  bool
+		existing  string
+		want      []string
+		absent    []string
+		wantCodes []string
+	}{
+		{
+			name:      "读取值",
+			want:      []string{"checksum/app-config", "checksum/db-secret", "checksums: checksum/app-config,checksum/db-secret\n"},
+			wantCodes: []string{utils.CodeChecksumUpdated, utils.CodeChecksumRemoved},
+		},
+		{
+			name:      "只读取键时保留已有注解",
+			keysOnly:  true,
+			existing:  "        checksum/db-secret: kept\n",
+			want:      []string{"checksum/app-config", "checksum/db-secret: kept", "checksums: checksum/app-config\n"},
+			wantCodes: []string{utils.CodeChecksumSkipped, utils.CodeChecksumUpdated, utils.CodeChecksumRemoved},
+		},
+		{
+			name:      "只读取键时不写入注解",
+			keysOnly:  true,
+			want:      []string{"checksum/app-config", "checksums: checksum/app-config\n"},
+			absent:    []string{"checksum/db-secret"},
+			wantCodes: []string{utils.CodeChecksumSkipped, utils.CodeChecksumUpdated, utils.CodeChecksumRemoved},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.ChecksumAnnotations = true
+				options.FromCluster = true
+				options.ClusterNamespaces = []string{utils.DefaultNamespace}
+				options.SecretKeysOnly = tt.keysOnly
+			})
+			p.ClusterClient = fake.NewClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: utils.DefaultNamespace, Name: "db-secret"},
+				Data:       map[string][]byte{"DB_PASSWORD": []byte("s3cret")},
+			})
+
+			input := testConfigMap + "---\n" + strings.Replace(checksumWorkload,
+				"        team: payments\n", "        team: payments\n"+tt.existing, 1)
+			output, err := p.ProcessContent(context.Background(), "app.yaml", []byte(input))
+			if err != nil {
+				t.Fatalf("ProcessContent: %v", err)
+			}
+			result := string(output)
+
+			for _, key := range tt.want {
+				if !strings.Contains(result, key) {
+					t.Errorf("输出中缺少 %s:\n%s", key, result)
+				}
+			}
+			absent := append([]string{"checksum/removed-config", "stale"}, tt.absent...)
+			for _, key := range absent {
+				if strings.Contains(result, key) {
+					t.Errorf("输出中不应包含 %s:\n%s", key, result)
+				}
+			}
+			// 其他工具写入的checksum/注解和缓存中不存在的配置的注解保持不变
+			for _, kept := range []string{"team: payments", "checksum/config: helm", "checksum/missing-config: external"} {
+				if !strings.Contains(result, kept) {
+					t.Errorf("注解 %s 应保留:\n%s", kept, result)
+				}
+			}
+
+			// 注解全部删除后不留下空的metadata
+			idle := result[strings.Index(result, "name: idle"):]
+			if strings.Contains(idle, "metadata:\n      annotations") || strings.Contains(idle, "{}") {
+				t.Errorf("空的注解和元数据应删除:\n%s", idle)
+			}
+
+			codes := make(map[string]bool)
+			for _, finding := range p.Report.Findings {
+				if strings.HasPrefix(finding.Code, "checksum-") {
+					codes[finding.Code] = true
+				}
+			}
+			for _, code := range tt.wantCodes {
+				if !codes[code] {
+					t.Errorf("报告中缺少 %s: %v", code, codes)
+				}
+			}
+			if len(codes) != len(tt.wantCodes) {
+				t.Errorf("报告中的注解条目 = %v, 期望 %v", codes, tt.wantCodes)
+			}
+
+			// 再次处理不产生变化
+			again, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				*options = *p.Options
+			})
+			again.ClusterClient = p.ClusterClient
+			rerun, err := again.ProcessContent(context.Background(), "app.yaml", output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(rerun) != result {
+				t.Errorf("再次处理的结果发生变化:\n%s", rerun)
+			}
+		})
+	}
+}
@@ -1,79 +1,89 @@
 package processor
 
 import (
-	"strings"
+	"encoding/base64"
+	"fmt"
 
 	"github.com/k8sconfig-processor/pkg/utils"
 )
 
-// 构建ConfigMap缓存
-func BuildConfigCache(resources []utils.KubeResource, cache *utils.ConfigCache) {
+// 构建ConfigMap缓存，返回无法解码的数据错误
+func BuildConfigCache(resources []utils.KubeResource, cache *utils.ConfigCache) []error {
+	var errs []error
+
 	for _, resource := range resources {
 		namespace := resource.Metadata.Namespace
 		name := resource.Metadata.Name
 
 		// 处理ConfigMap
-		if resource.Kind == utils.ConfigMapKind && resource.Data != nil {
+		if resource.Kind == utils.ConfigMapKind {
+			cache.ResetKeyFields(utils.ConfigMapKind, namespace, name)
+			data := make(map[string]string)
+			for key, value := range resource.Data {
+				data[key] = value
+				cache.SetKeyField(utils.ConfigMapKind, namespace, name, key, utils.DataField)
+			}
+
+			// binaryData中的值为base64编码，且不能与data中的键重复
+			binaryData := make(map[string]string)
+			for key, value := range resource.BinaryData {
+				if _, exists := data[key]; exists {
+					errs = append(errs, fmt.Errorf("ConfigMap %s/%s 的键 %s 同时出现在data和binaryData中", namespace, name, key))
+					continue
+				}
+
+				decoded, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("ConfigMap %s/%s 的binaryData键 %s 不是有效的base64: %v", namespace, name, key, err))
+					continue
+				}
+				binaryData[key] = string(decoded)
+				cache.SetKeyField(utils.ConfigMapKind, namespace, name, key, utils.BinaryDataField)
+			}
+
 			// 确保命名空间映射存在
 			if _, exists := cache.ConfigMaps[namespace]; !exists {
 				cache.ConfigMaps[namespace] = make(map[string]map[string]string)
+				cache.BinaryData[namespace] = make(map[string]map[string]string)
 			}
 
 			// 添加或更新ConfigMap数据
-			cache.ConfigMaps[namespace][name] = resource.Data
+			cache.ConfigMaps[namespace][name] = data
+			cache.BinaryData[namespace][name] = binaryData
 		}
 
 		// 处理Secret
 		if resource.Kind == utils.SecretKind {
+			cache.ResetKeyFields(utils.SecretKind, namespace, name)
+
 			// 确保命名空间映射存在
 			if _, exists := cache.Secrets[namespace]; !exists {
 				cache.Secrets[namespace] = make(map[string]map[string]string)
 			}
 
-			// 处理stringData字段
-			if resource.StringData != nil {
-				cache.Secrets[namespace][name] = resource.StringData
-			}
+			data := make(map[string]string)
 
-			// 处理data字段
-			if resource.Data != nil {
-				// 如果该命名空间下还没有该名称的Secret，则初始化
-				if _, exists := cache.Secrets[namespace][name]; !exists {
-					cache.Secrets[namespace][name] = make(map[string]string)
-				}
-
-				// 将data中的数据复制到缓存中
-				for key, value := range resource.Data {
-					cache.Secrets[namespace][name][key] = value
+			// 处理data字段，值为base64编码
+			for key, value := range resource.Data {
+				decoded, err := base64.StdEncoding.DecodeString(value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("Secret %s/%s 的data键 %s 不是有效的base64: %v", namespace, name, key, err))
+					continue
 				}
+				data[key] = string(decoded)
+				cache.SetKeyField(utils.SecretKind, namespace, name, key, utils.DataField)
 			}
-		}
-	}
-}
 
-// 从缓存中查找配置
-func FindConfigValue(envName string, namespace string, cache *utils.ConfigCache) (string, string, string, bool) {
-	// 生成配置对象名称（小写并替换下划线为中划线）
-	configName := strings.ToLower(strings.ReplaceAll(envName, "_", "-"))
-
-	// 首先检查ConfigMap
-	if namespaceConfigs, exists := cache.ConfigMaps[namespace]; exists {
-		if data, exists := namespaceConfigs[configName]; exists {
-			if value, exists := data[envName]; exists {
-				return value, configName, utils.ConfigMapKind, true
+			// 处理stringData字段，与Kubernetes一致，同名键以stringData为准
+			for key, value := range resource.StringData {
+				data[key] = value
+				cache.SetKeyField(utils.SecretKind, namespace, name, key, utils.StringDataField)
 			}
-		}
-	}
 
-	// 然后检查Secret
-	if namespaceSecrets, exists := cache.Secrets[namespace]; exists {
-		if data, exists := namespaceSecrets[configName]; exists {
-			if value, exists := data[envName]; exists {
-				return value, configName, utils.SecretKind, true
-			}
+			cache.Secrets[namespace][name] = data
+			cache.SetKeysOnly(namespace, name, resource.KeysOnly)
 		}
 	}
 
-	// 未找到配置
-	return "", "", "", false
+	return errs
 }
@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func TestBuildConfigCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		kind     string
+		want     map[string]string
+		// ConfigMap中解码后的binaryData
+		binary map[string]string
+		// 键的来源字段
+		fields map[string]string
+		// 期望的错误信息片段
+		wantErrs []string
+	}{
+		{
+			name: "ConfigMap的data和binaryData",
+			manifest: `kind: ConfigMap
+metadata:
+  name: app
+data:
+  LOG_LEVEL: info
+binaryData:
+  cert: aGVsbG8=
+`,
+			kind:   utils.ConfigMapKind,
+			want:   map[string]string{"LOG_LEVEL": "info"},
+			binary: map[string]string{"cert": "hello"},
+			fields: map[string]string{"LOG_LEVEL": utils.DataField, "cert": utils.BinaryDataField},
+		},
+		{
+			name: "Secret的data解码",
+			manifest: `kind: Secret
+metadata:
+  name: app
+data:
+  PASSWORD: czNjcmV0
+  EMPTY: ""
+`,
+			kind:   utils.SecretKind,
+			want:   map[string]string{"PASSWORD": "s3cret", "EMPTY": ""},
+			fields: map[string]string{"PASSWORD": utils.DataField, "EMPTY": utils.DataField},
+		},
+		{
+			name: "Secret的data不是有效的base64",
+			manifest: `kind: Secret
+metadata:
+  name: app
+data:
+  PASSWORD: s3cret!
+  USER: YWRtaW4=
+`,
+			kind:     utils.SecretKind,
+			want:     map[string]string{"USER": "admin"},
+			fields:   map[string]string{"USER": utils.DataField},
+			wantErrs: []string{"Secret default/app 的data键 PASSWORD 不是有效的base64"},
+		},
+		{
+			name: "同名键以stringData为准",
+			manifest: `kind: Secret
+metadata:
+  name: app
+data:
+  PASSWORD: b2xk
+  USER: YWRtaW4=
+stringData:
+  PASSWORD: new
+`,
+			kind:   utils.SecretKind,
+			want:   map[string]string{"PASSWORD": "new", "USER": "admin"},
+			fields: map[string]string{"PASSWORD": utils.StringDataField, "USER": utils.DataField},
+		},
+		{
+			name: "stringData覆盖无效的data",
+			manifest: `kind: Secret
+metadata:
+  name: app
+data:
+  PASSWORD: "!!!"
+stringData:
+  PASSWORD: plain
+`,
+			kind:     utils.SecretKind,
+			want:     map[string]string{"PASSWORD": "plain"},
+			fields:   map[string]string{"PASSWORD": utils.StringDataField},
+			wantErrs: []string{"data键 PASSWORD 不是有效的base64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resource utils.KubeResource
+			if err := yaml.Unmarshal([]byte(tt.manifest), &resource); err != nil {
+				t.Fatal(err)
+			}
+			resource.Metadata.Namespace = utils.DefaultNamespace
+
+			cache := utils.NewConfigCache()
+			errs := BuildConfigCache([]utils.KubeResource{resource}, cache)
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("BuildConfigCache() 错误 = %v, 期望 %v", errs, tt.wantErrs)
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("错误 %q 应包含 %q", errs[i], want)
+				}
+			}
+
+			if got := cacheObjects(cache, tt.kind)[utils.DefaultNamespace]["app"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("缓存数据 = %v, 期望 %v", got, tt.want)
+			}
+			if tt.kind == utils.ConfigMapKind {
+				if got := cache.BinaryData[utils.DefaultNamespace]["app"]; !reflect.DeepEqual(got, tt.binary) {
+					t.Errorf("binaryData = %v, 期望 %v", got, tt.binary)
+				}
+			}
+			for key, field := range tt.fields {
+				if got := cache.KeyField(tt.kind, utils.DefaultNamespace, "app", key); got != field {
+					t.Errorf("KeyField(%s) = %q, 期望 %q", key, got, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/k8sconfig-processor/pkg/diff"
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 单个文档的JSON Patch
+type documentPatch struct {
+	Index     int                     `json:"index"`
+	Kind      string                  `json:"kind"`
+	Namespace string                  `json:"namespace"`
+	Name      string                  `json:"name"`
+	Patch     []parser.PatchOperation `json:"patch"`
+}
+
+// 文件的JSON Patch
+type filePatch struct {
+	File      string          `json:"file"`
+	Documents []documentPatch `json:"documents"`
+}
+
+// 干运行模式：按选定的格式输出原文件与处理结果之间的差异
+func (p *MainProcessor) printDiff(out io.Writer, file *parser.File, yamlData []byte) error {
+	context := p.Options.DiffContext
+	if context < 0 {
+		context = utils.DefaultDiffContext
+	}
+
+	// 差异中使用相对于输入目录的路径，与git的a/、b/前缀一致
+	name := file.Path
+	if relPath, err := filepath.Rel(p.Options.InputDir, file.Path); err == nil {
+		name = filepath.ToSlash(relPath)
+	}
+
+	switch p.Options.DiffFormat {
+	case utils.DiffFormatSideBySide:
+		fmt.Fprint(out, diff.SideBySide(name, name+" (处理后)", file.Content, yamlData,
+			context, utils.SideBySideWidth, p.Options.Color))
+
+	case utils.DiffFormatJSONPatch:
+		patch := filePatch{File: name}
+		for index, document := range file.Documents {
+			operations, err := document.JSONPatch()
+			if err != nil {
+				return fmt.Errorf("生成JSON Patch失败: %v", err)
+			}
+			if len(operations) == 0 {
+				continue
+			}
+			patch.Documents = append(patch.Documents, documentPatch{
+				Index:     index,
+				Kind:      document.Resource.Kind,
+				Namespace: document.Resource.Metadata.Namespace,
+				Name:      document.Resource.Metadata.Name,
+				Patch:     operations,
+			})
+		}
+
+		data, err := json.MarshalIndent(patch, "", "  ")
+		if err != nil {
+			return fmt.Errorf("生成JSON Patch失败: %v", err)
+		}
+		fmt.Fprintln(out, string(data))
+
+	default:
+		fmt.Fprint(out, diff.Unified("a/"+name, "b/"+name, file.Content, yamlData,
+			context, p.Options.Color))
+	}
+
+	return nil
+}
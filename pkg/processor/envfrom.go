@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 容器envFrom中的配置来源
+type envFromSource struct {
+	// 配置对象类型: ConfigMap或Secret
+	Kind string
+	// 配置对象名称
+	Name string
+	// 环境变量名前缀
+	Prefix string
+}
+
+// 待填充的环境变量
+type pendingEnv struct {
+	// 环境变量节点
+	node *yaml.Node
+	// 环境变量名
+	name string
+	// 查找结果
+	resolution Resolution
+}
+
+// 解析容器现有的envFrom列表
+func envFromSources(container *yaml.Node) []envFromSource {
+	envFrom := parser.MappingValue(container, "envFrom")
+	if envFrom == nil || envFrom.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var sources []envFromSource
+	for _, item := range envFrom.Content {
+		prefix, _ := parser.MappingString(item, "prefix")
+		if name, ok := parser.MappingString(parser.MappingValue(item, "configMapRef"), "name"); ok {
+			sources = append(sources, envFromSource{Kind: utils.ConfigMapKind, Name: name, Prefix: prefix})
+		}
+		if name, ok := parser.MappingString(parser.MappingValue(item, "secretRef"), "name"); ok {
+			sources = append(sources, envFromSource{Kind: utils.SecretKind, Name: name, Prefix: prefix})
+		}
+	}
+
+	return sources
+}
+
+// 在容器现有的envFrom来源中查找环境变量
+func resolveFromEnvFrom(envName, namespace string, sources []envFromSource, cache *utils.ConfigCache) (Resolution, bool) {
+	// 后出现的来源覆盖先出现的来源
+	for i := len(sources) - 1; i >= 0; i-- {
+		source := sources[i]
+		if !strings.HasPrefix(envName, source.Prefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(envName, source.Prefix)
+		if value, exists := lookupKey(cacheObjects(cache, source.Kind), namespace, source.Name, key); exists {
+			return Resolution{
+				Value:      value,
+				ConfigName: source.Name,
+				ConfigKind: source.Kind,
+				Key:        key,
+				Strategy:   utils.ResolverEnvFrom,
+			}, true
+		}
+	}
+
+	return Resolution{}, false
+}
+
+// 判断同一配置对象的一组环境变量能否合并为envFrom：
+// 必须覆盖配置对象的全部键，且环境变量名与键之间的前缀一致
+func envFromPrefix(group []*pendingEnv, data map[string]string) (string, bool) {
+	if len(group) != len(data) {
+		return "", false
+	}
+
+	prefix := ""
+	keys := make(map[string]bool)
+	for i, pending := range group {
+		name, key := pending.name, pending.resolution.Key
+		if !strings.HasSuffix(name, key) {
+			return "", false
+		}
+
+		current := strings.TrimSuffix(name, key)
+		if i == 0 {
+			prefix = current
+		} else if current != prefix {
+			return "", false
+		}
+		keys[key] = true
+	}
+
+	for key := range data {
+		if !keys[key] {
+			return "", false
+		}
+	}
+
+	return prefix, true
+}
+
+// 创建envFrom列表项
+func newEnvFromSource(kind, name, prefix string) *yaml.Node {
+	refField := "configMapRef"
+	if kind == utils.SecretKind {
+		refField = "secretRef"
+	}
+
+	if prefix == "" {
+		return parser.NewMapping(refField, parser.NewMapping("name", name))
+	}
+	return parser.NewMapping("prefix", prefix, refField, parser.NewMapping("name", name))
+}
+
+// 创建valueFrom引用
+func newValueFrom(kind, name, key string) *yaml.Node {
+	keyRef := parser.NewMapping(
+		"name", name,
+		"key", key,
+	)
+
+	if kind == utils.SecretKind {
+		return parser.NewMapping("secretKeyRef", keyRef)
+	}
+	return parser.NewMapping("configMapKeyRef", keyRef)
+}
+
+// 获取指定类型的配置缓存
+func cacheObjects(cache *utils.ConfigCache, kind string) map[string]map[string]map[string]string {
+	if kind == utils.SecretKind {
+		return cache.Secrets
+	}
+	return cache.ConfigMaps
+}
+
+// 获取环境变量名列表
+func pendingNames(group []*pendingEnv) []string {
+	names := make([]string, 0, len(group))
+	for _, pending := range group {
+		names = append(names, pending.name)
+	}
+	sort.Strings(names)
+	return names
+}
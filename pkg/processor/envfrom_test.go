@@ -0,0 +1,191 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const envFromConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+  REGION: eu
+`
+
+// 使用紧凑序列风格的工作负载，env为空时不写入env字段
+func envFromWorkload(env, envFrom string) string {
+	workload := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`
+	if envFrom != "" {
+		workload += "        envFrom:\n" + envFrom
+	}
+	if env != "" {
+		workload += "        env:\n" + env
+	}
+	return workload
+}
+
+func TestEnvFromCollapse(t *testing.T) {
+	tests := []struct {
+		name     string
+		style    string
+		rules    map[string]string
+		env      string
+		envFrom  string
+		want     string
+		codes    []string
+		excluded []string
+	}{
+		{
+			name:     "auto低于阈值时逐个引用",
+			style:    utils.EnvStyleAuto,
+			env:      "        - name: LOG_LEVEL\n        - name: PORT\n",
+			codes:    []string{utils.CodeEnvResolved},
+			excluded: []string{"envFrom"},
+		},
+		{
+			name:  "auto达到阈值时合并",
+			style: utils.EnvStyleAuto,
+			env:   "        - name: LOG_LEVEL\n        - name: PORT\n        - name: REGION\n",
+			want: envFromWorkload("", `        - configMapRef:
+            name: app-config
+`),
+			codes: []string{utils.CodeEnvCollapsed},
+		},
+		{
+			name:  "envFrom不受阈值限制",
+			style: utils.EnvStyleEnvFrom,
+			env:   "        - name: LOG_LEVEL\n        - name: PORT\n        - name: REGION\n        - name: OTHER\n          value: x\n",
+			want: envFromWorkload("        - name: OTHER\n          value: x\n", "") +
+				"        envFrom:\n        - configMapRef:\n            name: app-config\n",
+			codes: []string{utils.CodeEnvCollapsed},
+		},
+		{
+			name:  "一致的前缀",
+			style: utils.EnvStyleEnvFrom,
+			rules: map[string]string{"APP_": "app-config"},
+			env:   "        - name: APP_LOG_LEVEL\n        - name: APP_PORT\n        - name: APP_REGION\n",
+			want: envFromWorkload("", `        - prefix: APP_
+          configMapRef:
+            name: app-config
+`),
+			codes: []string{utils.CodeEnvCollapsed},
+		},
+		{
+			name:     "前缀不一致时逐个引用",
+			style:    utils.EnvStyleEnvFrom,
+			rules:    map[string]string{"APP_": "app-config"},
+			env:      "        - name: APP_LOG_LEVEL\n        - name: APP_PORT\n        - name: REGION\n",
+			codes:    []string{utils.CodeEnvResolved},
+			excluded: []string{"envFrom", "prefix"},
+		},
+		{
+			name:     "未覆盖全部键时逐个引用",
+			style:    utils.EnvStyleEnvFrom,
+			env:      "        - name: LOG_LEVEL\n        - name: PORT\n",
+			codes:    []string{utils.CodeEnvResolved},
+			excluded: []string{"envFrom"},
+		},
+		{
+			name:    "移除已由envFrom提供的空定义",
+			style:   utils.EnvStyleEnvFrom,
+			envFrom: "        - configMapRef:\n            name: app-config\n",
+			env:     "        - name: OTHER\n          value: x\n        - name: PORT\n",
+			want: envFromWorkload("        - name: OTHER\n          value: x\n",
+				"        - configMapRef:\n            name: app-config\n"),
+			codes: []string{utils.CodeEnvRemoved},
+		},
+		{
+			name:    "auto同样移除空定义",
+			style:   utils.EnvStyleAuto,
+			envFrom: "        - prefix: APP_\n          configMapRef:\n            name: app-config\n",
+			env:     "        - name: APP_PORT\n",
+			want: envFromWorkload("",
+				"        - prefix: APP_\n          configMapRef:\n            name: app-config\n"),
+			codes: []string{utils.CodeEnvRemoved},
+		},
+		{
+			name:     "keyRef为envFrom提供的变量设置显式引用",
+			style:    utils.EnvStyleKeyRef,
+			envFrom:  "        - configMapRef:\n            name: app-config\n",
+			env:      "        - name: PORT\n",
+			codes:    []string{utils.CodeEnvResolved},
+			excluded: []string{utils.CodeEnvRemoved},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.EnvStyle = tt.style
+				options.PrefixRules = tt.rules
+				if tt.rules != nil {
+					options.Resolvers = []string{utils.ResolverPrefix, utils.ResolverKey}
+				}
+			})
+			files := loadTestFiles(t, p, map[string]string{
+				"config.yaml": envFromConfigMap,
+				"app.yaml":    envFromWorkload(tt.env, tt.envFrom),
+			})
+			if err := p.InitializeCache(context.Background(), files); err != nil {
+				t.Fatal(err)
+			}
+			p.ProcessFiles(files)
+
+			output := readOutputDir(t, p.Options.OutputDir)["app.yaml"]
+			if tt.want != "" && output != tt.want {
+				t.Errorf("输出 =\n%s\n期望\n%s", output, tt.want)
+			}
+			for _, text := range tt.excluded {
+				if strings.Contains(output, text) {
+					t.Errorf("输出不应包含 %s:\n%s", text, output)
+				}
+			}
+
+			codes := make(map[string]bool)
+			for _, finding := range p.Report.Findings {
+				codes[finding.Code] = true
+			}
+			for _, code := range tt.codes {
+				if !codes[code] {
+					t.Errorf("缺少 %s: %+v", code, p.Report.Findings)
+				}
+			}
+			for _, code := range tt.excluded {
+				if codes[code] {
+					t.Errorf("不应报告 %s: %+v", code, p.Report.Findings)
+				}
+			}
+		})
+	}
+}
+
+// 由现有envFrom提供的变量使用独立的策略名称，不与环境变量引用风格混用
+func TestResolveFromEnvFrom(t *testing.T) {
+	resource := utils.KubeResource{Kind: utils.ConfigMapKind, Data: map[string]string{"LEVEL": "info"}}
+	resource.Metadata.Namespace = utils.DefaultNamespace
+	resource.Metadata.Name = "log-config"
+	cache := utils.NewConfigCache()
+	BuildConfigCache([]utils.KubeResource{resource}, cache)
+
+	sources := []envFromSource{{Kind: utils.ConfigMapKind, Name: "log-config", Prefix: "LOG_"}}
+	resolution, found := resolveFromEnvFrom("LOG_LEVEL", utils.DefaultNamespace, sources, cache)
+	if !found || resolution.Key != "LEVEL" || resolution.Strategy != utils.ResolverEnvFrom {
+		t.Errorf("resolveFromEnvFrom(LOG_LEVEL) = %+v, %v, 期望键 LEVEL、策略 %s", resolution, found, utils.ResolverEnvFrom)
+	}
+}
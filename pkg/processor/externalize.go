@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/secrets"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 生成的配置清单开头的说明
+const externalizedHeader = "# 由 k8sconfig-processor externalize 生成，Secret中的值为明文，提交前请加密\n"
+
+// 将工作负载中env[].value的字面值移至生成的ConfigMap/Secret，并替换为valueFrom引用；
+// 名称匹配敏感模式或scan命令会报告为密钥的变量放入Secret，其余放入ConfigMap
+func (p *MainProcessor) Externalize() error {
+	target, err := p.externalizePath()
+	if err != nil {
+		return err
+	}
+
+	files, err := p.loadInput()
+	if err != nil {
+		return err
+	}
+	// 缺少文件时可能生成与已有对象冲突的配置
+	if len(p.loadErrors) > 0 {
+		return p.loadErrors[0]
+	}
+
+	// 已有的配置对象决定字面值能否复用或会产生冲突
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		return err
+	}
+
+	// 生成的清单已存在时在其基础上追加，重复执行不会产生变更
+	objects := make(map[configKey]map[string]string)
+	var existing *parser.File
+	inputs := make([]*parser.File, 0, len(files)+1)
+	for _, file := range files {
+		if samePath(file.Path, target) {
+			existing = file
+			p.collectGenerated(file, objects)
+			continue
+		}
+		inputs = append(inputs, file)
+	}
+
+	// 第一遍：按文件顺序移动字面值，同一个键的冲突与处理顺序无关
+	moved := make(map[*parser.File]bool)
+	for _, file := range inputs {
+		for _, document := range file.Documents {
+			if document.Root() != nil && p.externalizeDocument(document, objects) {
+				moved[file] = true
+			}
+		}
+	}
+
+	if len(objects) > 0 {
+		generated, changed, err := p.generatedConfigFile(target, externalizedHeader, existing, objects)
+		if err != nil {
+			return err
+		}
+		inputs = append(inputs, generated)
+		moved[generated] = changed
+		if existing == nil {
+			p.Report.TotalFiles++
+		}
+	} else if existing != nil {
+		inputs = append(inputs, existing)
+	}
+
+	// 第二遍：写入输出
+	p.processFiles(inputs, func(file *parser.File, worker *WorkloadProcessor) (bool, error) {
+		return moved[file], nil
+	})
+
+	p.Report.Sort()
+	return p.PrintReport()
+}
+
+// 生成的配置清单路径，以输入目录为基准，不允许绝对路径和输入目录之外的路径
+func (p *MainProcessor) externalizePath() (string, error) {
+	name := p.Options.ExternalizeFile
+	if name == "" {
+		name = utils.ExternalizedFile
+	}
+	if p.streaming() {
+		return name, nil
+	}
+
+	// 安全模式下写入输出目录中的对应位置，输入目录之外的路径会写到输出目录之外
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("生成的清单文件 %s 必须是相对于输入目录的路径", name)
+	}
+	target := filepath.Join(p.Options.InputDir, name)
+	relPath, err := filepath.Rel(p.Options.InputDir, target)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("生成的清单文件 %s 不能位于输入目录之外", name)
+	}
+	return target, nil
+}
+
+// 读取已生成的清单中的配置对象
+func (p *MainProcessor) collectGenerated(file *parser.File, objects map[configKey]map[string]string) {
+	for _, document := range file.Documents {
+		if document.Root() == nil {
+			continue
+		}
+		resource := &document.Resource
+		if resource.Kind != utils.ConfigMapKind && resource.Kind != utils.SecretKind {
+			continue
+		}
+
+		id := configKey{kind: resource.Kind, namespace: resource.Metadata.Namespace, name: resource.Metadata.Name}
+		data := make(map[string]string)
+		for key, value := range cacheObjects(p.ConfigCache, id.kind)[id.namespace][id.name] {
+			data[key] = value
+		}
+		objects[id] = data
+	}
+}
+
+// 移动文档中所有容器的字面值，返回是否修改
+func (p *MainProcessor) externalizeDocument(document *parser.Document, objects map[configKey]map[string]string) bool {
+	namespace := document.Resource.Metadata.Namespace
+	workload := document.Resource.Metadata.Name
+	modified := false
+
+	for _, spec := range p.WorkloadProcessor.PodSpecs(document) {
+		forEachContainer(spec, func(listName string, container *yaml.Node) {
+			env := parser.MappingValue(container, "env")
+			if env == nil || env.Kind != yaml.SequenceNode {
+				return
+			}
+
+			containerName, _ := parser.MappingString(container, "name")
+			location := ContainerLocation{
+				Namespace: namespace,
+				Workload:  workload,
+				List:      listName,
+				Container: containerName,
+				document:  document,
+			}
+
+			for _, envVar := range env.Content {
+				name, _ := parser.MappingString(envVar, "name")
+				value, ok := parser.MappingString(envVar, "value")
+				if name == "" || !ok || value == "" {
+					continue
+				}
+
+				// $(VAR)只在pod规格中展开，移至配置对象后会失效
+				if strings.Contains(value, "$(") {
+					finding := location.finding(utils.SeverityWarning, utils.CodeExternalizeSkip, envVar, name)
+					finding.Message = fmt.Sprintf("环境变量 %s 的值引用了其他环境变量，保留字面值 (%s)", name, location)
+					p.Report.Add(finding)
+					continue
+				}
+
+				kind := utils.ConfigMapKind
+				if secrets.IsSecret(name, value, p.Options.SecretPatterns) {
+					kind = utils.SecretKind
+				}
+				id := configKey{kind: kind, namespace: namespace, name: p.externalName(kind, name, workload)}
+
+				if reason := p.claimKey(objects, id, name, value); reason != "" {
+					finding := location.finding(utils.SeverityWarning, utils.CodeExternalizeSkip, envVar, name)
+					finding.Message = fmt.Sprintf("环境变量 %s 保留字面值: %s (%s)", name, reason, location)
+					finding.Suggestion = "使用--naming workload或重命名冲突的配置对象"
+					p.Report.Add(finding)
+					continue
+				}
+
+				parser.DeleteMappingValue(envVar, "value")
+				parser.SetMappingValue(envVar, "valueFrom", newValueFrom(kind, id.name, name))
+				modified = true
+
+				finding := location.finding(utils.SeverityInfo, utils.CodeEnvExternalized, envVar, name)
+				finding.Message = fmt.Sprintf("环境变量 %s 的值移至 %s %s (%s)", name, kind, id.name, location)
+				p.Report.Add(finding)
+			}
+		})
+	}
+
+	if modified {
+		p.Report.SuccessfulUpdates++
+	}
+	return modified
+}
+
+// 生成的配置对象名称，与同名的解析策略一致，处理后的清单可以被process命令识别
+func (p *MainProcessor) externalName(kind, envName, workload string) string {
+	if p.Options.ExternalizeNaming != utils.ResolverWorkload {
+		return ConfigNameForEnv(envName)
+	}
+	if kind == utils.SecretKind {
+		return workload + "-secret"
+	}
+	return workload + "-config"
+}
+
+// 在生成的配置对象中登记键；已有对象中存在相同的值时直接引用，返回无法登记的原因
+func (p *MainProcessor) claimKey(objects map[configKey]map[string]string, id configKey, key, value string) string {
+	if data, exists := objects[id]; exists {
+		if current, has := data[key]; has && current != value {
+			return fmt.Sprintf("%s %s/%s 中的键 %s 已有不同的值", id.kind, id.namespace, id.name, key)
+		}
+		data[key] = value
+		return ""
+	}
+
+	if data, exists := cacheObjects(p.ConfigCache, id.kind)[id.namespace][id.name]; exists {
+		current, has := data[key]
+		switch {
+		case has && current == value:
+			return ""
+		case has:
+			return fmt.Sprintf("%s %s/%s 中的键 %s 已有不同的值", id.kind, id.namespace, id.name, key)
+		default:
+			return fmt.Sprintf("%s %s/%s 已存在但不包含键 %s", id.kind, id.namespace, id.name, key)
+		}
+	}
+
+	objects[id] = map[string]string{key: value}
+	return ""
+}
+
+// 生成配置清单文件，返回文件以及内容是否变化
+func (p *MainProcessor) generatedConfigFile(path, header string, existing *parser.File, objects map[configKey]map[string]string) (*parser.File, bool, error) {
+	ids := make([]configKey, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.name < b.name
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, id := range ids {
+		metadata := parser.NewMapping("name", id.name)
+		if id.namespace != utils.DefaultNamespace {
+			parser.SetMappingValue(metadata, "namespace", parser.NewScalar(id.namespace))
+		}
+
+		data := parser.NewMapping()
+		for _, key := range sortedKeys(objects[id]) {
+			parser.SetMappingValue(data, key, parser.NewScalar(objects[id][key]))
+		}
+
+		node := parser.NewMapping("apiVersion", "v1", "kind", id.kind, "metadata", metadata)
+		if id.kind == utils.SecretKind {
+			parser.SetMappingValue(node, "type", parser.NewScalar("Opaque"))
+			parser.SetMappingValue(node, utils.StringDataField, data)
+		} else {
+			parser.SetMappingValue(node, utils.DataField, data)
+		}
+
+		if err := encoder.Encode(node); err != nil {
+			return nil, false, fmt.Errorf("生成配置清单失败: %v", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, false, fmt.Errorf("生成配置清单失败: %v", err)
+	}
+
+	// 使用独立的报告解析生成的内容，原始内容用于计算差异
+	file := parser.NewYAMLParser(p.ConfigCache, utils.NewProcessReport()).ParseContent(path, buf.Bytes())
+	if existing == nil {
+		file.Content = nil
+		return file, true, nil
+	}
+	file.Content = existing.Content
+	return file, !bytes.Equal(existing.Content, buf.Bytes()), nil
+}
@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/k8sconfig-processor/pkg/kustomize"
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 分析输入中的kustomization文件：资源文件中的文档使用kustomization的命名空间，
+// 生成器产生的配置以及在多个命名空间中构建的配置对象作为额外的配置层
+func (p *MainProcessor) loadKustomizations(files []*parser.File) (configLayer, error) {
+	layer := configLayer{priority: utils.InputSourcePriority, name: "kustomize"}
+
+	var kustomizationFiles []string
+	for _, file := range files {
+		if kustomize.IsKustomizationFile(file.Path) {
+			kustomizationFiles = append(kustomizationFiles, file.Path)
+		}
+	}
+	if len(kustomizationFiles) == 0 {
+		return layer, nil
+	}
+
+	result, err := kustomize.Analyze(kustomizationFiles)
+	if err != nil {
+		return layer, fmt.Errorf("分析kustomization失败: %v", err)
+	}
+	layer.resources = result.Resources
+
+	p.namespaceContexts = make(map[*parser.Document][]string)
+	for _, file := range files {
+		builds := result.Namespaces[parser.AbsPath(file.Path)]
+		if len(builds) == 0 {
+			continue
+		}
+
+		for _, document := range file.Documents {
+			if document.Root() == nil {
+				continue
+			}
+
+			// 只修改内存中的命名空间，输出的文件不变
+			namespaces := buildNamespaces(document.Resource.Metadata.Namespace, builds)
+			document.Resource.Metadata.Namespace = namespaces[0]
+			if len(namespaces) == 1 {
+				continue
+			}
+
+			p.namespaceContexts[document] = namespaces
+			// 配置对象在每个构建的命名空间中都可见
+			kind := document.Resource.Kind
+			if kind == utils.ConfigMapKind || kind == utils.SecretKind {
+				for _, namespace := range namespaces[1:] {
+					resource := document.Resource
+					resource.Metadata.Namespace = namespace
+					layer.resources = append(layer.resources, resource)
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(p.Log, "kustomization: %d 个，生成 %d 个ConfigMap/Secret\n", len(kustomizationFiles), len(result.Resources))
+	return layer, nil
+}
+
+// 文档在各个构建中的命名空间，去重并保持顺序；未设置命名空间的构建保留文档自身的命名空间
+func buildNamespaces(own string, builds []string) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, namespace := range builds {
+		if namespace == "" {
+			namespace = own
+		}
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// 处理工作负载；被多个命名空间的overlay引用的基础文档在每个命名空间中分别试处理，
+// 只有结果完全相同时才修改文档，否则保留原样并报告
+func (p *MainProcessor) processWorkload(worker *WorkloadProcessor, document *parser.Document, namespaces []string) (bool, error) {
+	if len(namespaces) < 2 {
+		return worker.ProcessWorkload(document)
+	}
+
+	var rendered []byte
+	for i, namespace := range namespaces {
+		scratchReport := utils.NewProcessReport()
+		scratchFile := parser.NewYAMLParser(p.ConfigCache, scratchReport).ParseContent(document.Path, document.Raw)
+		if len(scratchFile.Documents) != 1 || scratchFile.Documents[0].Root() == nil {
+			return worker.ProcessWorkload(document)
+		}
+		scratch := scratchFile.Documents[0]
+		scratch.Resource.Metadata.Namespace = namespace
+
+		scratchWorker := worker.WithReport(scratchReport)
+		scratchWorker.stubs = worker.stubs.scratch()
+		if _, err := scratchWorker.ProcessWorkload(scratch); err != nil {
+			return false, err
+		}
+		data, err := scratch.Render()
+		if err != nil {
+			return false, err
+		}
+
+		if i > 0 && !bytes.Equal(data, rendered) {
+			worker.Report.Add(utils.Finding{
+				Severity:   utils.SeverityWarning,
+				Code:       utils.CodeOverlayConflict,
+				File:       document.Path,
+				Line:       document.StartLine,
+				Namespace:  namespace,
+				Workload:   document.Resource.Metadata.Name,
+				Message:    fmt.Sprintf("资源 %s 在命名空间 %s 和 %s 中的解析结果不同，未修改", document.Resource.Metadata.Name, namespaces[0], namespace),
+				Suggestion: "在各个overlay中提供同名的配置对象，或在overlay中用补丁设置valueFrom",
+			})
+			return false, nil
+		}
+		rendered = data
+	}
+
+	return worker.ProcessWorkload(document)
+}
@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 没有扩展名的Kustomization文件被扫描，其中生成器产生的配置用于查找
+func TestKustomizationFileWithoutExtension(t *testing.T) {
+	p, _ := newTestProcessor(t, nil)
+	files := loadTestFiles(t, p, map[string]string{
+		"app/" + utils.KustomizationFile: `resources:
+  - deployment.yaml
+configMapGenerator:
+  - name: log-level
+    literals:
+      - LOG_LEVEL=debug
+`,
+		"app/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`,
+	})
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		t.Fatal(err)
+	}
+	p.ProcessFiles(files)
+
+	output := readOutputDir(t, p.Options.OutputDir)["app/deployment.yaml"]
+	if !strings.Contains(output, "name: log-level") {
+		t.Errorf("应引用生成器产生的log-level:\n%s", output)
+	}
+}
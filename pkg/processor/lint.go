@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 检查所有文件中已有的引用，问题记入报告，不修改任何文件
+func (p *MainProcessor) Lint() error {
+	files, err := p.loadInput()
+	if err != nil {
+		return err
+	}
+
+	// 初始化配置缓存，缺少文件时无法判断引用是否有效
+	if len(p.loadErrors) > 0 {
+		return p.loadErrors[0]
+	}
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		for _, document := range file.Documents {
+			if document.Root() != nil {
+				reportReferences(p.WorkloadProcessor, document, utils.SeverityError)
+			}
+		}
+	}
+
+	p.Report.Sort()
+	return p.PrintReport()
+}
@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 预检查发现的问题
+type PrecheckIssue struct {
+	// 是否为阻断性问题
+	Blocking bool
+	// 问题描述
+	Message string
+}
+
+// 预检查结果
+type PrecheckResult struct {
+	Issues []PrecheckIssue
+}
+
+// 添加问题
+func (r *PrecheckResult) add(blocking bool, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, PrecheckIssue{
+		Blocking: blocking,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// 阻断性问题的数量
+func (r *PrecheckResult) BlockingCount() int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Blocking {
+			count++
+		}
+	}
+	return count
+}
+
+// 执行只读的预检查，不修改任何文件，也不影响处理报告
+func (p *MainProcessor) Precheck(files []*parser.File) *PrecheckResult {
+	result := &PrecheckResult{}
+
+	// 无法读取的文件
+	for _, err := range p.loadErrors {
+		result.add(true, "无法读取文件: %v", err)
+	}
+
+	// 使用独立的报告，避免预检查的结果混入正式处理报告
+	scratchReport := utils.NewProcessReport()
+	scratchParser := parser.NewYAMLParser(p.ConfigCache, scratchReport)
+	scratch := p.WorkloadProcessor.WithReport(scratchReport)
+	scratch.stubs = scratch.stubs.scratch()
+
+	// 配置对象定义位置: 类型/命名空间/名称 -> 文件:行号
+	definitions := make(map[string]string)
+	// 输出路径 -> 输入文件
+	outputs := make(map[string]string)
+
+	for _, loaded := range files {
+		// 试运行会修改节点树，从已读取的内容重新解析一份
+		filePath := loaded.Path
+		file := scratchParser.ParseContent(filePath, loaded.Content)
+
+		for i, document := range file.Documents {
+			// 保留加载时确定的命名空间（例如来自kustomization）
+			var namespaces []string
+			if i < len(loaded.Documents) && document.Root() != nil && loaded.Documents[i].Root() != nil {
+				document.Resource.Metadata.Namespace = loaded.Documents[i].Resource.Metadata.Namespace
+				namespaces = p.namespaceContexts[loaded.Documents[i]]
+			}
+
+			// 无法解析的文档
+			if document.Err != nil {
+				result.add(true, "无法解析文档 %s:%d: %v", filePath, document.StartLine, document.Err)
+				continue
+			}
+			if document.Root() == nil {
+				continue
+			}
+
+			resource := &document.Resource
+
+			// 重复定义的配置对象
+			if resource.Kind == utils.ConfigMapKind || resource.Kind == utils.SecretKind {
+				id := fmt.Sprintf("%s %s/%s", resource.Kind, resource.Metadata.Namespace, resource.Metadata.Name)
+				position := fmt.Sprintf("%s:%d", filePath, document.Line(document.Root()))
+				if previous, exists := definitions[id]; exists {
+					result.add(true, "%s 重复定义: %s 与 %s", id, previous, position)
+				} else {
+					definitions[id] = position
+				}
+			}
+
+			// 已有valueFrom引用指向不存在的对象或键，optional引用不阻断
+			for _, issue := range scratch.CheckReferences(document) {
+				result.add(!issue.Optional, "%s:%d: %s", filePath, issue.Line, issue.Message)
+			}
+
+			// 试运行处理，收集无法解析的环境变量等警告
+			if _, err := p.processWorkload(scratch, document, namespaces); err != nil {
+				result.add(true, "处理资源失败: %s: %v", filePath, err)
+			}
+		}
+
+		// 输出路径冲突
+		if p.Options.Mode == utils.ModeSafe && !p.Options.Check && !p.streaming() {
+			outputPath := p.outputPath(filePath)
+			if samePath(outputPath, filePath) {
+				result.add(true, "输出文件会覆盖输入文件: %s", filePath)
+			}
+			if previous, exists := outputs[outputPath]; exists {
+				result.add(true, "输出路径冲突: %s 与 %s 都会写入 %s", previous, filePath, outputPath)
+			}
+			if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
+				result.add(true, "输出路径是一个目录: %s", outputPath)
+			}
+			outputs[outputPath] = filePath
+		}
+	}
+
+	for _, warning := range scratchReport.Warnings {
+		result.add(false, "%s", warning)
+	}
+
+	return result
+}
+
+// 打印预检查结果
+func (p *MainProcessor) PrintPrecheck(result *PrecheckResult) {
+	fmt.Fprintln(p.Log, "\n===== 预检查 =====")
+	if len(result.Issues) == 0 {
+		fmt.Fprintln(p.Log, "未发现问题")
+		return
+	}
+
+	for _, issue := range result.Issues {
+		if issue.Blocking {
+			fmt.Fprintf(p.Log, "- [阻断] %s\n", issue.Message)
+		} else {
+			fmt.Fprintf(p.Log, "- [警告] %s\n", issue.Message)
+		}
+	}
+	fmt.Fprintf(p.Log, "阻断性问题: %d, 警告: %d\n", result.BlockingCount(), len(result.Issues)-result.BlockingCount())
+}
+
+// 判断两个路径是否指向同一文件
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const precheckWorkload = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`
+
+// 阻断性问题使处理在写入任何文件之前停止，只有警告时正常处理
+func TestPrecheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		blocking int
+		warnings int
+		message  string
+	}{
+		{
+			name:  "没有问题",
+			files: map[string]string{"config.yaml": testConfigMap, "app.yaml": precheckWorkload},
+		},
+		{
+			name: "重复定义的配置对象",
+			files: map[string]string{
+				"config.yaml": testConfigMap,
+				"copy.yaml":   testConfigMap,
+				"app.yaml":    precheckWorkload,
+			},
+			blocking: 1,
+			message:  "重复定义",
+		},
+		{
+			name: "无法解析的文档",
+			files: map[string]string{
+				"config.yaml": testConfigMap,
+				"app.yaml":    precheckWorkload + "---\nkind: [broken\n",
+			},
+			blocking: 1,
+			message:  "无法解析文档",
+		},
+		{
+			name: "引用不存在的对象",
+			files: map[string]string{
+				"config.yaml": testConfigMap,
+				"app.yaml": precheckWorkload + `            - name: DB_HOST
+              valueFrom:
+                configMapKeyRef:
+                  name: db-config
+                  key: DB_HOST
+`,
+			},
+			blocking: 1,
+			message:  "db-config",
+		},
+		{
+			name: "optional引用只是警告",
+			files: map[string]string{
+				"config.yaml": testConfigMap,
+				"app.yaml": precheckWorkload + `            - name: DB_HOST
+              valueFrom:
+                configMapKeyRef:
+                  name: db-config
+                  key: DB_HOST
+                  optional: true
+`,
+			},
+			warnings: 1,
+			message:  "db-config",
+		},
+		{
+			name:     "未解析的环境变量只是警告",
+			files:    map[string]string{"config.yaml": testConfigMap, "app.yaml": precheckWorkload + "            - name: REGION\n"},
+			warnings: 1,
+			message:  "REGION",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.Precheck = true
+			})
+			log := &strings.Builder{}
+			p.Log = log
+			writeFiles(t, p.Options.InputDir, tt.files)
+
+			err := p.Execute()
+			if (err != nil) != (tt.blocking > 0) {
+				t.Fatalf("Execute() = %v, 阻断性问题 %d:\n%s", err, tt.blocking, log.String())
+			}
+			if tt.blocking+tt.warnings > 0 {
+				want := fmt.Sprintf("阻断性问题: %d, 警告: %d", tt.blocking, tt.warnings)
+				if !strings.Contains(log.String(), want) {
+					t.Errorf("预检查输出应包含 %q:\n%s", want, log.String())
+				}
+			} else if !strings.Contains(log.String(), "未发现问题") {
+				t.Errorf("预检查应未发现问题:\n%s", log.String())
+			}
+			if tt.message != "" && !strings.Contains(log.String(), tt.message) {
+				t.Errorf("预检查输出应包含 %q:\n%s", tt.message, log.String())
+			}
+
+			// 阻断时不写入任何文件，否则处理LOG_LEVEL
+			output := readOutputDir(t, p.Options.OutputDir)
+			if tt.blocking > 0 {
+				if len(output) != 0 {
+					t.Errorf("阻断时不应写入文件: %v", output)
+				}
+				return
+			}
+			if !strings.Contains(output["app.yaml"], "key: LOG_LEVEL") {
+				t.Errorf("只有警告时应正常处理:\n%s", output["app.yaml"])
+			}
+		})
+	}
+}
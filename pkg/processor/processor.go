@@ -1,12 +1,21 @@
 package processor
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 
+	"github.com/k8sconfig-processor/pkg/cluster"
 	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/report"
 	"github.com/k8sconfig-processor/pkg/utils"
+	"k8s.io/client-go/kubernetes"
 )
 
 // 主处理器
@@ -23,15 +32,59 @@ type MainProcessor struct {
 	Options *utils.ProcessOptions
 	// 缓存是否已初始化
 	CacheInitialized bool
+
+	// 处理结果（差异或处理后的YAML流）的输出
+	Output io.Writer
+	// 提示信息和报告的输出
+	Log io.Writer
+
+	// 额外的配置来源，在--config-source之后加载
+	Sources []PrioritySource
+
+	// 集群客户端，为空时根据kubeconfig创建；可以注入fake clientset
+	ClusterClient kubernetes.Interface
+
+	// 读取失败的文件
+	loadErrors []error
+	// 被多个不同命名空间的overlay引用的文档及其命名空间
+	namespaceContexts map[*parser.Document][]string
+	// 流模式中是否已输出过文档，之后的输出需要文档分隔符
+	streamed bool
 }
 
 // 创建新的主处理器
-func NewMainProcessor(options *utils.ProcessOptions) *MainProcessor {
+func NewMainProcessor(options *utils.ProcessOptions) (*MainProcessor, error) {
 	configCache := utils.NewConfigCache()
 	report := utils.NewProcessReport()
 
+	// 未指定解析策略时使用默认顺序
+	resolverNames := options.Resolvers
+	if len(resolverNames) == 0 {
+		resolverNames = utils.DefaultResolvers
+	}
+	resolver, err := NewResolverChain(resolverNames, options.PrefixRules, configCache)
+	if err != nil {
+		return nil, err
+	}
+
 	yamlParser := parser.NewYAMLParser(configCache, report)
-	workloadProcessor := NewWorkloadProcessor(configCache, report)
+	workloadProcessor := NewWorkloadProcessor(configCache, report, resolver)
+
+	// 加载自定义工作负载定义
+	if options.WorkloadConfig != "" {
+		if err := workloadProcessor.Registry.LoadConfig(options.WorkloadConfig); err != nil {
+			return nil, err
+		}
+	}
+	workloadProcessor.Registry.Discover = options.DiscoverContainers
+
+	if options.EnvStyle != "" {
+		workloadProcessor.EnvStyle = options.EnvStyle
+	}
+	workloadProcessor.Checksums = options.ChecksumAnnotations
+	if options.CreateMissing {
+		workloadProcessor.stubs = newStubSet(options.SecretPatterns)
+	}
 
 	return &MainProcessor{
 		Parser:            yamlParser,
@@ -40,130 +93,440 @@ func NewMainProcessor(options *utils.ProcessOptions) *MainProcessor {
 		Report:            report,
 		Options:           options,
 		CacheInitialized:  false,
+		Output:            os.Stdout,
+		Log:               os.Stdout,
+	}, nil
+}
+
+// 目录扫描选项：跳过输出目录，避免重复处理上一次的输出
+func (p *MainProcessor) scanOptions() parser.ScanOptions {
+	options := parser.ScanOptions{
+		Include: p.Options.Include,
+		Exclude: p.Options.Exclude,
+	}
+	if p.Options.OutputDir != "" {
+		options.ExcludeDirs = append(options.ExcludeDirs, p.Options.OutputDir)
 	}
+	return options
 }
 
-// 处理单个文件
-func (p *MainProcessor) ProcessFile(filePath string) error {
-	// 解析文件
-	resources, err := p.Parser.ParseFile(filePath)
-	if err != nil {
-		p.Report.Errors = append(p.Report.Errors,
-			fmt.Sprintf("处理文件失败: %s: %v", filePath, err))
-		return err
+// 并发读取并解析所有文件，每个文件只解析一次，解析错误按文件顺序记入报告
+func (p *MainProcessor) LoadFiles(yamlFiles []string) []*parser.File {
+	files := make([]*parser.File, len(yamlFiles))
+	reports := make([]*utils.ProcessReport, len(yamlFiles))
+	errs := make([]error, len(yamlFiles))
+
+	p.runParallel(len(yamlFiles), func(i int) {
+		reports[i] = utils.NewProcessReport()
+		files[i], errs[i] = parser.NewYAMLParser(p.ConfigCache, reports[i]).ParseFile(yamlFiles[i])
+	}, func(i int) {
+		p.Report.Merge(reports[i])
+	})
+
+	var loaded []*parser.File
+	for i, file := range files {
+		if errs[i] != nil {
+			p.Report.Add(utils.Finding{
+				Severity: utils.SeverityError,
+				Code:     utils.CodeProcessError,
+				File:     yamlFiles[i],
+				Message:  fmt.Sprintf("读取文件失败: %v", errs[i]),
+			})
+			p.loadErrors = append(p.loadErrors, fmt.Errorf("%s: %v", yamlFiles[i], errs[i]))
+			continue
+		}
+		loaded = append(loaded, file)
+	}
+
+	return loaded
+}
+
+// 并发处理所有文件，每个文件使用独立的报告，按文件顺序输出并合并到主报告
+func (p *MainProcessor) ProcessFiles(files []*parser.File) {
+	p.processFiles(files, p.processFile)
+}
+
+// 使用指定的处理函数并发处理所有文件并写入输出
+func (p *MainProcessor) processFiles(files []*parser.File, process func(file *parser.File, worker *WorkloadProcessor) (bool, error)) {
+	reports := make([]*utils.ProcessReport, len(files))
+	outputs := make([]*bytes.Buffer, len(files))
+	errs := make([]error, len(files))
+
+	p.runParallel(len(files), func(i int) {
+		reports[i] = utils.NewProcessReport()
+		outputs[i] = &bytes.Buffer{}
+		modified, err := process(files[i], p.WorkloadProcessor.WithReport(reports[i]))
+		if err == nil && modified {
+			reports[i].ProcessedFiles++
+		}
+		// 流模式输出所有文件，未修改的文件原样输出
+		if err == nil && (modified || p.streaming()) {
+			err = p.writeOutput(files[i], modified, outputs[i])
+		}
+		errs[i] = err
+	}, func(i int) {
+		// 流模式中多个文件之间需要文档分隔符
+		if p.streaming() && !p.Options.Check && p.Options.Mode != utils.ModeDryRun && outputs[i].Len() > 0 {
+			if p.streamed && !bytes.HasPrefix(outputs[i].Bytes(), []byte("---")) {
+				io.WriteString(p.Output, "---\n")
+			}
+			p.streamed = true
+		}
+		p.Output.Write(outputs[i].Bytes())
+		if errs[i] != nil {
+			fmt.Fprintf(p.Log, "处理文件 %s 时出错: %v\n", files[i].Path, errs[i])
+		}
+		p.Report.Merge(reports[i])
+	})
+
+	p.Report.Sort()
+}
+
+// 使用有限数量的协程执行任务，并按下标顺序对已完成的任务调用finish
+func (p *MainProcessor) runParallel(count int, task func(i int), finish func(i int)) {
+	jobs := p.Options.Jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > count {
+		jobs = count
+	}
+
+	queue := make(chan int)
+	done := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				task(i)
+				done <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := 0; i < count; i++ {
+			queue <- i
+		}
+		close(queue)
+		wg.Wait()
+		close(done)
+	}()
+
+	// 按顺序输出已完成的任务，保证结果与调度无关
+	finished := make([]bool, count)
+	next := 0
+	for i := range done {
+		finished[i] = true
+		for next < count && finished[next] {
+			finish(next)
+			next++
+		}
+	}
+}
+
+// 处理单个文件中的所有文档，报告写入worker的报告，返回文件是否被修改
+func (p *MainProcessor) processFile(file *parser.File, worker *WorkloadProcessor) (bool, error) {
+	filePath := file.Path
+
+	// 按策略跳过包含无法解析文档的文件；否则这些文档在输出中原样保留
+	if file.HasErrors() && p.Options.OnParseError == utils.ParseErrorSkip {
+		worker.Report.Add(utils.Finding{
+			Severity: utils.SeverityWarning,
+			Code:     utils.CodeFileSkipped,
+			File:     filePath,
+			Message:  "文件包含无法解析的文档，已跳过整个文件",
+		})
+		return false, nil
 	}
 
 	// 只处理工作负载资源，不更新缓存
 	var modified bool
-	var modifiedResources []utils.KubeResource
 
-	for i := range resources {
-		resource := &resources[i]
+	for _, document := range file.Documents {
+		if document.Root() == nil {
+			continue
+		}
 
 		// 处理工作负载
-		resourceModified, err := p.WorkloadProcessor.ProcessWorkload(resource)
+		resourceModified, err := p.processWorkload(worker, document, p.namespaceContexts[document])
 		if err != nil {
-			p.Report.Errors = append(p.Report.Errors,
-				fmt.Sprintf("处理资源失败: %s: %v", filePath, err))
+			worker.Report.Add(utils.Finding{
+				Severity:  utils.SeverityError,
+				Code:      utils.CodeProcessError,
+				File:      filePath,
+				Line:      document.Line(document.Root()),
+				Namespace: document.Resource.Metadata.Namespace,
+				Workload:  document.Resource.Metadata.Name,
+				Message:   fmt.Sprintf("处理资源失败: %v", err),
+			})
 			continue
 		}
 
-		modifiedResources = append(modifiedResources, *resource)
 		if resourceModified {
 			modified = true
 		}
-	}
 
-	// 如果文件被修改，写入输出
-	if modified {
-		p.Report.ProcessedFiles++
-		return p.writeOutput(filePath, modifiedResources)
+		// 检查处理后的所有引用，对象可能由清单以外的来源创建，缺失只作为警告
+		reportReferences(worker, document, utils.SeverityWarning)
 	}
 
-	return nil
+	return modified, nil
+}
+
+// 检查文档中的所有引用并按severity记入报告，optional引用缺失只作为警告
+func reportReferences(worker *WorkloadProcessor, document *parser.Document, severity string) {
+	for _, issue := range worker.CheckReferences(document) {
+		finding := utils.Finding{
+			Severity:   severity,
+			Code:       utils.CodeMissingReference,
+			File:       document.Path,
+			Line:       issue.Line,
+			Namespace:  document.Resource.Metadata.Namespace,
+			Workload:   document.Resource.Metadata.Name,
+			Container:  issue.Container,
+			EnvVar:     issue.EnvVar,
+			Message:    issue.Message,
+			Suggestion: "创建被引用的对象或键，或修正引用的名称",
+		}
+		if issue.Optional {
+			finding.Severity = utils.SeverityWarning
+			finding.Message += " (optional)"
+		}
+		worker.Report.Add(finding)
+	}
 }
 
 // 写入输出
-func (p *MainProcessor) writeOutput(filePath string, resources []utils.KubeResource) error {
-	// 转换为YAML
-	yamlData, err := p.Parser.EncodeToYAML(resources)
+func (p *MainProcessor) writeOutput(file *parser.File, modified bool, out io.Writer) error {
+	filePath := file.Path
+
+	// 检查模式：只报告尚未处理的文件
+	if p.Options.Check {
+		if modified {
+			fmt.Fprintf(out, "需要处理: %s\n", filePath)
+		}
+		return nil
+	}
+
+	// 转换为YAML，未修改的部分保持原样
+	yamlData, err := p.Parser.EncodeFile(file)
 	if err != nil {
 		return err
 	}
 
-	// 根据输出模式处理
-	var outputPath string
-
-	if p.Options.Mode == utils.ModeSafe {
-		// 安全模式：输出到新目录
-		relPath, err := filepath.Rel(p.Options.InputDir, filePath)
-		if err != nil {
-			relPath = filepath.Base(filePath)
+	// 流模式：干运行时输出差异，否则输出处理后的YAML
+	if p.streaming() {
+		if p.Options.Mode == utils.ModeDryRun {
+			if !modified {
+				return nil
+			}
+			return p.printDiff(out, file, yamlData)
+		}
+		out.Write(yamlData)
+		if len(yamlData) > 0 && yamlData[len(yamlData)-1] != '\n' {
+			io.WriteString(out, "\n")
 		}
+		return nil
+	}
 
-		outputPath = filepath.Join(p.Options.OutputDir, relPath)
+	// 根据输出模式处理
+	outputPath := p.outputPath(filePath)
 
-		// 确保输出目录存在
+	if p.Options.Mode == utils.ModeSafe {
+		// 安全模式：确保输出目录存在
 		outDir := filepath.Dir(outputPath)
 		if err := os.MkdirAll(outDir, 0755); err != nil {
 			return err
 		}
 
-	} else if p.Options.Mode == utils.ModeOverwrite {
-		// 覆盖模式：直接写回原文件
-		outputPath = filePath
 	} else if p.Options.Mode == utils.ModeDryRun {
 		// 干运行模式：输出差异
-		fmt.Printf("--- 原文件: %s\n", filePath)
-		fmt.Printf("+++ 处理后:\n")
-		fmt.Println(string(yamlData))
-		return nil
+		return p.printDiff(out, file, yamlData)
 	}
 
 	// 写入文件
 	return os.WriteFile(outputPath, yamlData, 0644)
 }
 
-// 初始化配置缓存
-func (p *MainProcessor) InitializeCache(yamlFiles []string) error {
+// 计算输入文件对应的输出路径
+func (p *MainProcessor) outputPath(filePath string) string {
+	if p.Options.Mode != utils.ModeSafe {
+		// 覆盖模式：直接写回原文件
+		return filePath
+	}
+
+	// 安全模式：输出到新目录
+	relPath, err := filepath.Rel(p.Options.InputDir, filePath)
+	if err != nil {
+		relPath = filepath.Base(filePath)
+	}
+
+	return filepath.Join(p.Options.OutputDir, relPath)
+}
+
+// 一个配置来源中已解析的文件，或直接读取的资源（例如来自集群）
+type configLayer struct {
+	priority  int
+	files     []*parser.File
+	name      string
+	resources []utils.KubeResource
+}
+
+// 读取--config-source指定的配置来源以及额外的配置来源
+func (p *MainProcessor) loadConfigSources(ctx context.Context) ([]configLayer, error) {
+	var layers []configLayer
+
+	for _, source := range p.Options.ConfigSources {
+		info, err := os.Stat(source.Path)
+		if err != nil {
+			return nil, &SourceError{Source: source.Path, Err: err}
+		}
+
+		paths := []string{source.Path}
+		if info.IsDir() {
+			// 使用独立的解析器扫描，不影响输入目录的文件统计
+			scanner := parser.NewYAMLParser(p.ConfigCache, utils.NewProcessReport())
+			paths, err = scanner.ScanDirectory(source.Path, parser.ScanOptions{})
+			if err != nil {
+				return nil, &SourceError{Source: source.Path, Err: err}
+			}
+		}
+
+		files := p.LoadFiles(paths)
+		fmt.Fprintf(p.Log, "配置来源 %s (优先级 %d): %d 个YAML文件\n", source.Path, source.Priority, len(files))
+		layers = append(layers, configLayer{priority: source.Priority, files: files})
+	}
+
+	for _, source := range p.Sources {
+		resources, err := source.Source.Load(ctx)
+		if err != nil {
+			return nil, &SourceError{Source: source.Source.Name(), Err: err}
+		}
+		fmt.Fprintf(p.Log, "配置来源 %s (优先级 %d): %d 个ConfigMap/Secret\n", source.Source.Name(), source.Priority, len(resources))
+		layers = append(layers, configLayer{priority: source.Priority, name: source.Source.Name(), resources: resources})
+	}
+
+	if p.Options.FromCluster {
+		layer, err := p.loadClusterSource(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// 放在最前面，优先级相同时文件中的配置优先
+		layers = append([]configLayer{layer}, layers...)
+	}
+
+	return layers, nil
+}
+
+// 从集群列出ConfigMap和Secret，只需要list权限
+func (p *MainProcessor) loadClusterSource(ctx context.Context) (configLayer, error) {
+	clusterOptions := cluster.Options{
+		Kubeconfig:     p.Options.Kubeconfig,
+		Context:        p.Options.KubeContext,
+		Namespaces:     p.Options.ClusterNamespaces,
+		SecretKeysOnly: p.Options.SecretKeysOnly,
+	}
+
+	client := p.ClusterClient
+	namespaces := clusterOptions.Namespaces
+	if client == nil {
+		var contextNamespace string
+		var err error
+		client, contextNamespace, err = cluster.NewClient(clusterOptions)
+		if err != nil {
+			return configLayer{}, &SourceError{Source: "cluster", Err: err}
+		}
+		if len(namespaces) == 0 {
+			namespaces = []string{contextNamespace}
+		}
+	}
+
+	source := NewClusterSource(client, namespaces, clusterOptions.SecretKeysOnly).(*clusterSource)
+	if clusterOptions.Context != "" {
+		source.name += ":" + clusterOptions.Context
+	}
+
+	resources, err := source.Load(ctx)
+	if err != nil {
+		return configLayer{}, &SourceError{Source: source.Name(), Err: err}
+	}
+	fmt.Fprintf(p.Log, "配置来源 %s (优先级 %d): %d 个ConfigMap/Secret\n", source.Name(), utils.DefaultSourcePriority, len(resources))
+
+	return configLayer{priority: utils.DefaultSourcePriority, name: source.Name(), resources: resources}, nil
+}
+
+// 初始化配置缓存：依次加载配置来源和输入目录，同名对象以优先级高的为准，
+// 优先级相同时以后加载的为准
+func (p *MainProcessor) InitializeCache(ctx context.Context, files []*parser.File) error {
 	// 如果缓存已初始化，则跳过
 	if p.CacheInitialized {
 		return nil
 	}
 
-	// 加载所有配置文件到缓存中
-	for _, file := range yamlFiles {
-		resources, err := p.Parser.ParseFile(file)
-		if err != nil {
-			fmt.Printf("解析文件 %s 时出错: %v\n", file, err)
-			continue
+	layers, err := p.loadConfigSources(ctx)
+	if err != nil {
+		return err
+	}
+	kustomizeLayer, err := p.loadKustomizations(files)
+	if err != nil {
+		return err
+	}
+	layers = append(layers, configLayer{priority: utils.InputSourcePriority, files: files}, kustomizeLayer)
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].priority < layers[j].priority
+	})
+
+	// 按优先级从低到高加载，高优先级的同名对象覆盖低优先级的
+	for _, layer := range layers {
+		for _, parsed := range layer.files {
+			var resources []utils.KubeResource
+			for _, document := range parsed.Documents {
+				if document.Root() != nil {
+					resources = append(resources, document.Resource)
+				}
+			}
+			p.buildCache(parsed.Path, resources)
+		}
+		if len(layer.resources) > 0 {
+			p.buildCache(layer.name, layer.resources)
 		}
-
-		BuildConfigCache(resources, p.ConfigCache)
 	}
 
 	p.CacheInitialized = true
 
 	// 调试：打印缓存内容
 	if p.Options.Mode == utils.ModeDryRun {
-		fmt.Println("\n=== ConfigMap缓存内容 ===")
-		for namespace, namespaceConfigs := range p.ConfigCache.ConfigMaps {
-			fmt.Printf("  命名空间: %s\n", namespace)
-			for name, data := range namespaceConfigs {
-				fmt.Printf("    ConfigMap: %s\n", name)
-				for key, value := range data {
-					fmt.Printf("      %s: %s\n", key, value)
+		// 按名称排序输出，保证结果稳定
+		fmt.Fprintln(p.Log, "\n=== ConfigMap缓存内容 ===")
+		for _, namespace := range sortedKeys(p.ConfigCache.ConfigMaps) {
+			fmt.Fprintf(p.Log, "  命名空间: %s\n", namespace)
+			namespaceConfigs := p.ConfigCache.ConfigMaps[namespace]
+			for _, name := range sortedKeys(namespaceConfigs) {
+				fmt.Fprintf(p.Log, "    ConfigMap: %s\n", name)
+				data := namespaceConfigs[name]
+				for _, key := range sortedKeys(data) {
+					fmt.Fprintf(p.Log, "      %s: %s\n", key, data[key])
+				}
+				binaryData := p.ConfigCache.BinaryData[namespace][name]
+				for _, key := range sortedKeys(binaryData) {
+					fmt.Fprintf(p.Log, "      %s: <binaryData, %d字节>\n", key, len(binaryData[key]))
 				}
 			}
 		}
 
-		fmt.Println("\n=== Secret缓存内容 ===")
-		for namespace, namespaceSecrets := range p.ConfigCache.Secrets {
-			fmt.Printf("  命名空间: %s\n", namespace)
-			for name, data := range namespaceSecrets {
-				fmt.Printf("    Secret: %s\n", name)
-				for key, value := range data {
-					fmt.Printf("      %s: %s\n", key, value)
+		fmt.Fprintln(p.Log, "\n=== Secret缓存内容 ===")
+		for _, namespace := range sortedKeys(p.ConfigCache.Secrets) {
+			fmt.Fprintf(p.Log, "  命名空间: %s\n", namespace)
+			namespaceSecrets := p.ConfigCache.Secrets[namespace]
+			for _, name := range sortedKeys(namespaceSecrets) {
+				fmt.Fprintf(p.Log, "    Secret: %s\n", name)
+				// 只输出键，Secret的值（包括来自集群的）不能出现在日志中
+				data := namespaceSecrets[name]
+				for _, key := range sortedKeys(data) {
+					fmt.Fprintf(p.Log, "      %s: <已隐藏, %d字节>\n", key, len(data[key]))
 				}
 			}
 		}
@@ -172,52 +535,147 @@ func (p *MainProcessor) InitializeCache(yamlFiles []string) error {
 	return nil
 }
 
+// 将资源加入配置缓存，无效的配置记入报告
+func (p *MainProcessor) buildCache(source string, resources []utils.KubeResource) {
+	for _, err := range BuildConfigCache(resources, p.ConfigCache) {
+		p.Report.Add(utils.Finding{
+			Severity: utils.SeverityError,
+			Code:     utils.CodeInvalidConfig,
+			File:     source,
+			Message:  fmt.Sprintf("加载配置失败: %v", err),
+		})
+	}
+}
+
+// 获取映射中排序后的键
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 读取输入：流模式读取指定的文件和标准输入，否则扫描输入目录
+func (p *MainProcessor) loadInput() ([]*parser.File, error) {
+	if p.streaming() {
+		return p.LoadStream(os.Stdin)
+	}
+
+	// 扫描目录
+	yamlFiles, err := p.Parser.ScanDirectory(p.Options.InputDir, p.scanOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(p.Log, "找到 %d 个YAML文件\n", len(yamlFiles))
+
+	// 读取并解析所有文件，解析结果在后续步骤中复用
+	return p.LoadFiles(yamlFiles), nil
+}
+
 // 执行处理
 func (p *MainProcessor) Execute() error {
-	// 扫描目录
-	yamlFiles, err := p.Parser.ScanDirectory(p.Options.InputDir)
+	files, err := p.loadInput()
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("找到 %d 个YAML文件\n", len(yamlFiles))
-
 	// 初始化配置缓存
-	if err := p.InitializeCache(yamlFiles); err != nil {
+	if err := p.InitializeCache(context.Background(), files); err != nil {
 		return err
 	}
 
-	// 处理所有文件
-	for _, file := range yamlFiles {
-		if err := p.ProcessFile(file); err != nil {
-			fmt.Printf("处理文件 %s 时出错: %v\n", file, err)
+	// 已生成的占位清单在最后与新的占位对象合并写入，其中的名称可以继续使用
+	workloads, stubs := p.splitStubsFile(files)
+	p.WorkloadProcessor.stubs.adopt(stubs)
+
+	// 预检查：在写入任何文件之前发现问题
+	if p.Options.Precheck {
+		result := p.Precheck(files)
+		p.PrintPrecheck(result)
+		if count := result.BlockingCount(); count > 0 {
+			return fmt.Errorf("预检查发现 %d 个阻断性问题，未写入任何文件", count)
 		}
 	}
 
+	// 处理所有文件
+	p.ProcessFiles(workloads)
+	if err := p.writeStubs(stubs); err != nil {
+		return err
+	}
+
 	// 输出报告
-	p.PrintReport()
+	return p.PrintReport()
+}
 
-	return nil
+// 处理内存中的多文档YAML，不读写磁盘，返回处理后的内容；未修改的文档原样返回
+func (p *MainProcessor) ProcessContent(ctx context.Context, name string, data []byte) ([]byte, error) {
+	file := p.Parser.ParseContent(name, data)
+	p.Report.TotalFiles = 1
+
+	if err := p.InitializeCache(ctx, []*parser.File{file}); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	modified, err := p.processFile(file, p.WorkloadProcessor)
+	if err != nil {
+		return nil, err
+	}
+	if modified {
+		p.Report.ProcessedFiles++
+	}
+	p.Report.Sort()
+
+	output, err := p.Parser.EncodeFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// 占位配置对象作为额外的文档追加在末尾
+	stubs, _, err := p.stubsFile(nil)
+	if err != nil || stubs == nil {
+		return output, err
+	}
+	stubsOutput, err := p.Parser.EncodeFile(stubs)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		output = append(output, '\n')
+	}
+	output = append(output, "---\n"...)
+	return append(output, stubsOutput...), nil
 }
 
-// 打印报告
-func (p *MainProcessor) PrintReport() {
-	fmt.Println("\n===== 处理报告 =====")
-	fmt.Printf("文件总数: %d\n", p.Report.TotalFiles)
-	fmt.Printf("处理的文件数: %d\n", p.Report.ProcessedFiles)
-	fmt.Printf("成功更新的资源数: %d\n", p.Report.SuccessfulUpdates)
+// 根据报告和失败条件计算进程退出码，检查模式下任何变更都视为失败
+func (p *MainProcessor) ExitCode() int {
+	failOn := p.Options.FailOn
+	if p.Options.Check {
+		failOn = utils.FailOnChange
+	}
+	return p.Report.ExitCode(failOn)
+}
 
-	if len(p.Report.Warnings) > 0 {
-		fmt.Println("\n警告:")
-		for _, warning := range p.Report.Warnings {
-			fmt.Printf("- %s\n", warning)
-		}
+// 按选项的格式输出报告，指定了报告文件时写入文件
+func (p *MainProcessor) PrintReport() error {
+	if p.Options.ReportFile == "" {
+		return report.Write(p.Log, p.Options.ReportFormat, p.Report)
 	}
 
-	if len(p.Report.Errors) > 0 {
-		fmt.Println("\n错误:")
-		for _, err := range p.Report.Errors {
-			fmt.Printf("- %s\n", err)
-		}
+	file, err := os.Create(p.Options.ReportFile)
+	if err != nil {
+		return fmt.Errorf("无法创建报告文件: %v", err)
 	}
+	defer file.Close()
+
+	if err := report.Write(file, p.Options.ReportFormat, p.Report); err != nil {
+		return fmt.Errorf("写入报告失败: %v", err)
+	}
+	fmt.Fprintf(p.Log, "报告已写入 %s\n", p.Options.ReportFile)
+	return nil
 }
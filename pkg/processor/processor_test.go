@@ -0,0 +1,461 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/diff"
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const testConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+  PORT: "8080"
+`
+
+// 创建写入内存的处理器，输入和输出目录为临时目录
+func newTestProcessor(t *testing.T, configure func(options *utils.ProcessOptions)) (*MainProcessor, *bytes.Buffer) {
+	t.Helper()
+	options := &utils.ProcessOptions{
+		InputDir:     t.TempDir(),
+		OutputDir:    t.TempDir(),
+		Mode:         utils.ModeSafe,
+		Resolvers:    utils.DefaultResolvers,
+		EnvStyle:     utils.EnvStyleKeyRef,
+		OnParseError: utils.ParseErrorKeep,
+		DiffContext:  3,
+	}
+	if configure != nil {
+		configure(options)
+	}
+
+	p, err := NewMainProcessor(options)
+	if err != nil {
+		t.Fatalf("NewMainProcessor: %v", err)
+	}
+	output := &bytes.Buffer{}
+	p.Output = output
+	p.Log = &bytes.Buffer{}
+	return p, output
+}
+
+// 在输入目录中写入文件并读取解析
+func loadTestFiles(t *testing.T, p *MainProcessor, files map[string]string) []*parser.File {
+	t.Helper()
+	var paths []string
+	for name, content := range files {
+		filePath := filepath.Join(p.Options.InputDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filePath)
+	}
+
+	scanned, err := p.Parser.ScanDirectory(p.Options.InputDir, p.scanOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != len(paths) {
+		t.Fatalf("扫描到%d个文件, 期望%d个", len(scanned), len(paths))
+	}
+	return p.LoadFiles(scanned)
+}
+
+// 包含无法解析文档的文件：keep原样保留该文档并处理其余文档，skip不写入整个文件
+func TestParseErrorPolicy(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`
+	broken := "kind: Service\nspec: [unclosed\n"
+	input := deployment + "---\n" + broken + "---\n" + deployment
+
+	tests := []struct {
+		policy      string
+		wantWritten bool
+		wantCode    string
+	}{
+		{utils.ParseErrorKeep, true, ""},
+		{utils.ParseErrorSkip, false, utils.CodeFileSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.OnParseError = tt.policy
+			})
+			files := loadTestFiles(t, p, map[string]string{
+				"config.yaml": testConfigMap,
+				"app.yaml":    input,
+			})
+			if err := p.InitializeCache(context.Background(), files); err != nil {
+				t.Fatal(err)
+			}
+			p.ProcessFiles(files)
+
+			outputPath := filepath.Join(p.Options.OutputDir, "app.yaml")
+			output, err := os.ReadFile(outputPath)
+			if !tt.wantWritten {
+				if !os.IsNotExist(err) {
+					t.Errorf("跳过的文件不应写入: %v\n%s", err, output)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("读取输出失败: %v", err)
+				}
+				offset := bytes.Index(output, []byte("---\n"+broken+"---\n"))
+				if offset < 0 {
+					t.Fatalf("无法解析的文档没有原样保留:\n%s", output)
+				}
+				if bytes.Count(output[:offset], []byte("app-config")) != 1 || bytes.Count(output[offset:], []byte("app-config")) != 1 {
+					t.Errorf("无法解析的文档前后的工作负载都应被处理:\n%s", output)
+				}
+			}
+
+			var codes []string
+			for _, finding := range p.Report.Findings {
+				codes = append(codes, finding.Code)
+			}
+			if !containsString(codes, utils.CodeParseError) {
+				t.Errorf("报告中缺少解析错误: %v", codes)
+			}
+			if tt.wantCode != "" && !containsString(codes, tt.wantCode) {
+				t.Errorf("报告中缺少 %s: %v", tt.wantCode, codes)
+			}
+		})
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 生成一组需要处理的文件：可以解析的、缺少配置的、需要占位对象的以及无法解析的
+func writeDeterminismFixtures(t *testing.T, dir string) []string {
+	t.Helper()
+	files := map[string]string{
+		"config/config.yaml": testConfigMap + `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db-secret
+stringData:
+  DB_PASSWORD: s3cret
+`,
+	}
+	for i := 0; i < 40; i++ {
+		var content string
+		switch i % 4 {
+		case 0:
+			content = fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%02d
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: LOG_LEVEL
+            - name: DB_PASSWORD
+`, i)
+		case 1:
+			// 无法解析的配置，报告中应出现错误
+			content = fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: missing-%02d
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          env:
+            - name: UNKNOWN_%02d
+            - name: PORT
+          envFrom:
+            - configMapRef:
+                name: nowhere-%02d
+`, i, i%8, i)
+		case 2:
+			content = fmt.Sprintf("apiVersion: v1\nkind: Service\nmetadata:\n  name: svc-%02d\n", i)
+		case 3:
+			content = fmt.Sprintf(`apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: job-%02d
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: job
+              env:
+                - name: PORT
+---
+kind: Broken
+spec: [unclosed
+`, i)
+		}
+		files[fmt.Sprintf("apps/%02d/app.yaml", i)] = content
+	}
+
+	var paths []string
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// 读取输出目录中的所有文件，按相对路径索引
+func readOutputDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	result := make(map[string]string)
+	err := filepath.WalkDir(dir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, filePath)
+		result[relPath] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// 并发处理的结果与顺序处理完全一致：报告、写入的文件和流式输出都与调度无关
+func TestProcessFilesDeterministic(t *testing.T) {
+	inputDir := t.TempDir()
+	paths := writeDeterminismFixtures(t, inputDir)
+
+	type result struct {
+		findings []utils.Finding
+		output   string
+		files    map[string]string
+	}
+
+	run := func(t *testing.T, jobs int, configure func(options *utils.ProcessOptions)) result {
+		p, output := newTestProcessor(t, func(options *utils.ProcessOptions) {
+			options.InputDir = inputDir
+			options.Jobs = jobs
+			options.ChecksumAnnotations = true
+			options.CreateMissing = true
+			configure(options)
+		})
+		if err := p.Execute(); err != nil {
+			t.Fatalf("Execute (jobs=%d): %v", jobs, err)
+		}
+		return result{
+			findings: p.Report.Findings,
+			output:   output.String(),
+			files:    readOutputDir(t, p.Options.OutputDir),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		configure func(options *utils.ProcessOptions)
+	}{
+		{"安全模式", func(options *utils.ProcessOptions) {}},
+		{"干运行", func(options *utils.ProcessOptions) { options.Mode = utils.ModeDryRun }},
+		{"流模式", func(options *utils.ProcessOptions) { options.Files = paths }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequential := run(t, 1, tt.configure)
+			if len(sequential.findings) == 0 {
+				t.Fatal("测试数据应产生报告条目")
+			}
+			if sequential.output == "" && len(sequential.files) == 0 {
+				t.Fatal("测试数据应产生输出")
+			}
+
+			// 多次运行以增加发现调度相关差异的机会
+			for i := 0; i < 5; i++ {
+				parallel := run(t, 8, tt.configure)
+				if !reflect.DeepEqual(sequential.findings, parallel.findings) {
+					t.Fatalf("jobs=8 的报告与 jobs=1 不一致:\njobs=1: %+v\njobs=8: %+v", sequential.findings, parallel.findings)
+				}
+				if sequential.output != parallel.output {
+					t.Fatalf("jobs=8 的输出与 jobs=1 不一致:\n%s", diff.Unified("jobs=1", "jobs=8",
+						[]byte(sequential.output), []byte(parallel.output), 3, false))
+				}
+				if !reflect.DeepEqual(sequential.files, parallel.files) {
+					t.Fatalf("jobs=8 写入的文件与 jobs=1 不一致")
+				}
+			}
+		})
+	}
+}
+
+// 退出码由报告中最高的级别决定，低于--fail-on的级别时为0；检查模式按change判断
+func TestExitCode(t *testing.T) {
+	severities := map[string]string{
+		"info":    utils.SeverityInfo,
+		"warning": utils.SeverityWarning,
+		"error":   utils.SeverityError,
+	}
+
+	tests := []struct {
+		highest string
+		failOn  string
+		check   bool
+		want    int
+	}{
+		{"none", utils.FailOnChange, false, utils.ExitClean},
+		{"none", utils.FailOnWarning, false, utils.ExitClean},
+		{"none", utils.FailOnError, false, utils.ExitClean},
+		{"info", utils.FailOnChange, false, utils.ExitChanges},
+		{"info", utils.FailOnWarning, false, utils.ExitClean},
+		{"info", utils.FailOnError, false, utils.ExitClean},
+		{"warning", utils.FailOnChange, false, utils.ExitWarnings},
+		{"warning", utils.FailOnWarning, false, utils.ExitWarnings},
+		{"warning", utils.FailOnError, false, utils.ExitClean},
+		{"error", utils.FailOnChange, false, utils.ExitErrors},
+		{"error", utils.FailOnWarning, false, utils.ExitErrors},
+		{"error", utils.FailOnError, false, utils.ExitErrors},
+		// 检查模式中任何变更都以非零状态退出
+		{"none", utils.FailOnError, true, utils.ExitClean},
+		{"info", utils.FailOnError, true, utils.ExitChanges},
+		{"warning", utils.FailOnError, true, utils.ExitWarnings},
+		{"error", utils.FailOnError, true, utils.ExitErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%s/check=%v", tt.highest, tt.failOn, tt.check), func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.FailOn = tt.failOn
+				options.Check = tt.check
+			})
+			// 添加不超过最高级别的各级条目
+			for _, name := range []string{"info", "warning", "error"} {
+				if tt.highest == "none" {
+					break
+				}
+				p.Report.Add(utils.Finding{Severity: severities[name], Code: name, Message: name})
+				if name == tt.highest {
+					break
+				}
+			}
+
+			if got := p.ExitCode(); got != tt.want {
+				t.Errorf("ExitCode() = %d, 期望 %d", got, tt.want)
+			}
+			// ExitFailure只用于执行失败，不由报告产生
+			if got := p.ExitCode(); got == utils.ExitFailure {
+				t.Errorf("报告不应产生退出码 %d", utils.ExitFailure)
+			}
+		})
+	}
+}
+
+// 目录模式的检查：不写入任何文件，列出需要处理的文件，有待处理的变更时以非零状态退出
+func TestCheckDirectory(t *testing.T) {
+	workload := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`
+	done := strings.Replace(workload, "            - name: LOG_LEVEL\n", `            - name: LOG_LEVEL
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: LOG_LEVEL
+`, 1)
+
+	for _, mode := range []string{utils.ModeSafe, utils.ModeOverwrite} {
+		t.Run(mode, func(t *testing.T) {
+			p, output := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.Mode = mode
+				options.Check = true
+			})
+			files := map[string]string{
+				"config.yaml":  testConfigMap,
+				"pending.yaml": workload,
+				"done.yaml":    done,
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(p.Options.InputDir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := p.Execute(); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+
+			for name, content := range files {
+				data, err := os.ReadFile(filepath.Join(p.Options.InputDir, name))
+				if err != nil || string(data) != content {
+					t.Errorf("检查模式不应修改 %s: %v\n%s", name, err, data)
+				}
+			}
+			if entries, _ := os.ReadDir(p.Options.OutputDir); len(entries) > 0 {
+				t.Errorf("检查模式不应写入输出目录: %v", entries)
+			}
+
+			listed := output.String()
+			if !strings.Contains(listed, "需要处理: "+filepath.Join(p.Options.InputDir, "pending.yaml")) {
+				t.Errorf("应列出需要处理的文件:\n%s", listed)
+			}
+			if strings.Contains(listed, "done.yaml") || strings.Contains(listed, "config.yaml") {
+				t.Errorf("已处理的文件不应列出:\n%s", listed)
+			}
+			if code := p.ExitCode(); code != utils.ExitChanges {
+				t.Errorf("ExitCode() = %d, 期望 %d", code, utils.ExitChanges)
+			}
+		})
+	}
+}
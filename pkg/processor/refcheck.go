@@ -0,0 +1,240 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 引用检查发现的问题
+type ReferenceIssue struct {
+	// 问题所在行号
+	Line int
+	// 引用是否标记为optional
+	Optional bool
+	// 所在的容器和环境变量，卷引用为空
+	Container string
+	EnvVar    string
+	// 问题描述
+	Message string
+}
+
+// 单个文档的引用检查器
+type referenceChecker struct {
+	cache     *utils.ConfigCache
+	stubs     *stubSet
+	document  *parser.Document
+	namespace string
+	issues    []ReferenceIssue
+
+	// 正在检查的容器和环境变量
+	container string
+	envVar    string
+}
+
+// 检查资源中已有的引用是否指向存在的配置对象和键：
+// env的valueFrom、envFrom，以及configMap、secret和projected卷
+func (p *WorkloadProcessor) CheckReferences(document *parser.Document) []ReferenceIssue {
+	resource := &document.Resource
+	checker := &referenceChecker{
+		cache:     p.ConfigCache,
+		stubs:     p.stubs,
+		document:  document,
+		namespace: resource.Metadata.Namespace,
+	}
+
+	for _, spec := range p.PodSpecs(document) {
+		forEachContainer(spec, func(listName string, container *yaml.Node) {
+			containerName, _ := parser.MappingString(container, "name")
+			location := ContainerLocation{
+				Namespace: checker.namespace,
+				Workload:  resource.Metadata.Name,
+				List:      listName,
+				Container: containerName,
+			}
+			checker.container = containerName
+			checker.checkContainer(container, location.String())
+			checker.container = ""
+		})
+
+		volumes := parser.MappingValue(spec, "volumes")
+		if volumes == nil || volumes.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, volume := range volumes.Content {
+			volumeName, _ := parser.MappingString(volume, "name")
+			location := fmt.Sprintf("资源: %s/%s, volumes: %s", checker.namespace, resource.Metadata.Name, volumeName)
+			checker.checkVolume(volume, location)
+		}
+	}
+
+	return checker.issues
+}
+
+// 检查容器的env和envFrom引用
+func (c *referenceChecker) checkContainer(container *yaml.Node, location string) {
+	env := parser.MappingValue(container, "env")
+	if env != nil && env.Kind == yaml.SequenceNode {
+		for _, envVar := range env.Content {
+			envName, _ := parser.MappingString(envVar, "name")
+			valueFrom := parser.MappingValue(envVar, "valueFrom")
+			subject := "环境变量 " + envName
+			c.envVar = envName
+
+			if ref := parser.MappingValue(valueFrom, "configMapKeyRef"); ref != nil {
+				c.checkKeyRef(ref, utils.ConfigMapKind, subject, location)
+			}
+			if ref := parser.MappingValue(valueFrom, "secretKeyRef"); ref != nil {
+				c.checkKeyRef(ref, utils.SecretKind, subject, location)
+			}
+		}
+		c.envVar = ""
+	}
+
+	envFrom := parser.MappingValue(container, "envFrom")
+	if envFrom != nil && envFrom.Kind == yaml.SequenceNode {
+		for _, item := range envFrom.Content {
+			if ref := parser.MappingValue(item, "configMapRef"); ref != nil {
+				name, _ := parser.MappingString(ref, "name")
+				c.checkObject(ref, utils.ConfigMapKind, name, isOptional(ref), "envFrom", location)
+			}
+			if ref := parser.MappingValue(item, "secretRef"); ref != nil {
+				name, _ := parser.MappingString(ref, "name")
+				c.checkObject(ref, utils.SecretKind, name, isOptional(ref), "envFrom", location)
+			}
+		}
+	}
+}
+
+// 检查configMap、secret和projected卷
+func (c *referenceChecker) checkVolume(volume *yaml.Node, location string) {
+	if source := parser.MappingValue(volume, "configMap"); source != nil {
+		c.checkVolumeSource(source, utils.ConfigMapKind, "name", "configMap卷", location)
+	}
+	if source := parser.MappingValue(volume, "secret"); source != nil {
+		c.checkVolumeSource(source, utils.SecretKind, "secretName", "secret卷", location)
+	}
+
+	sources := parser.LookupPath(volume, "projected", "sources")
+	if sources == nil || sources.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, projection := range sources.Content {
+		if source := parser.MappingValue(projection, "configMap"); source != nil {
+			c.checkVolumeSource(source, utils.ConfigMapKind, "name", "projected卷", location)
+		}
+		if source := parser.MappingValue(projection, "secret"); source != nil {
+			c.checkVolumeSource(source, utils.SecretKind, "name", "projected卷", location)
+		}
+	}
+}
+
+// 检查卷来源引用的对象及items中的键
+func (c *referenceChecker) checkVolumeSource(source *yaml.Node, kind, nameField, subject, location string) {
+	name, _ := parser.MappingString(source, nameField)
+	optional := isOptional(source)
+
+	data, exists := c.checkObject(source, kind, name, optional, subject, location)
+	if !exists {
+		return
+	}
+
+	items := parser.MappingValue(source, "items")
+	if items == nil || items.Kind != yaml.SequenceNode {
+		return
+	}
+	for _, item := range items.Content {
+		key, _ := parser.MappingString(item, "key")
+		if _, exists := data[key]; !exists {
+			c.add(item, optional, "%s 引用的%s %s/%s 不包含键 %s (%s)", subject, kind, c.namespace, name, key, location)
+		}
+	}
+}
+
+// 检查configMapKeyRef或secretKeyRef
+func (c *referenceChecker) checkKeyRef(ref *yaml.Node, kind, subject, location string) {
+	name, _ := parser.MappingString(ref, "name")
+	key, _ := parser.MappingString(ref, "key")
+	optional := isOptional(ref)
+
+	data, exists := cacheObjects(c.cache, kind)[c.namespace][name]
+	if !exists {
+		c.checkObject(ref, kind, name, optional, subject, location)
+		return
+	}
+
+	// 环境变量只能引用data中的键，binaryData中的键不可用；本次处理追加到该对象的占位键也会被写入
+	_, stubbed := c.stubs.lookup(kind, c.namespace, name)[key]
+	if _, exists := data[key]; !exists && !stubbed {
+		c.add(ref, optional, "%s 引用的%s %s/%s 不包含键 %s (%s)", subject, kind, c.namespace, name, key, location)
+	}
+}
+
+// 检查配置对象是否存在，返回对象的全部键（包括binaryData）
+func (c *referenceChecker) checkObject(node *yaml.Node, kind, name string, optional bool, subject, location string) (map[string]string, bool) {
+	data, exists := cacheObjects(c.cache, kind)[c.namespace][name]
+	// 本次处理生成的占位对象也会被写入
+	if stubData := c.stubs.lookup(kind, c.namespace, name); stubData != nil {
+		for key, value := range data {
+			if _, has := stubData[key]; !has {
+				stubData[key] = value
+			}
+		}
+		data, exists = stubData, true
+	}
+	if exists {
+		if kind == utils.ConfigMapKind && len(c.cache.BinaryData[c.namespace][name]) > 0 {
+			merged := make(map[string]string)
+			for key, value := range data {
+				merged[key] = value
+			}
+			for key, value := range c.cache.BinaryData[c.namespace][name] {
+				merged[key] = value
+			}
+			data = merged
+		}
+		return data, true
+	}
+
+	// 同名对象存在于其他命名空间，通常是命名空间写错
+	if others := c.otherNamespaces(kind, name); len(others) > 0 {
+		c.add(node, optional, "%s 引用的%s %s/%s 不存在，但命名空间 %v 中有同名对象，引用只能指向同一命名空间 (%s)",
+			subject, kind, c.namespace, name, others, location)
+	} else {
+		c.add(node, optional, "%s 引用的%s %s/%s 不存在 (%s)", subject, kind, c.namespace, name, location)
+	}
+
+	return nil, false
+}
+
+// 查找其他命名空间中的同名配置对象
+func (c *referenceChecker) otherNamespaces(kind, name string) []string {
+	var namespaces []string
+	for namespace, objects := range cacheObjects(c.cache, kind) {
+		if _, exists := objects[name]; exists && namespace != c.namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// 添加问题
+func (c *referenceChecker) add(node *yaml.Node, optional bool, format string, args ...interface{}) {
+	c.issues = append(c.issues, ReferenceIssue{
+		Line:      c.document.Line(node),
+		Optional:  optional,
+		Container: c.container,
+		EnvVar:    c.envVar,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// 判断引用是否标记为optional
+func isOptional(ref *yaml.Node) bool {
+	optional, _ := parser.MappingString(ref, "optional")
+	return optional == "true"
+}
@@ -0,0 +1,320 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+const refcheckConfig = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+binaryData:
+  logo.png: aGVsbG8=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app-secret
+stringData:
+  PASSWORD: s3cret
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: prod-config
+  namespace: prod
+data:
+  LOG_LEVEL: warn
+`
+
+// 包含给定容器字段和卷的Deployment
+func refcheckWorkload(container, volumes string) string {
+	workload := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+` + container
+	if volumes != "" {
+		workload += "      volumes:\n" + volumes
+	}
+	return workload
+}
+
+func TestCheckReferences(t *testing.T) {
+	tests := []struct {
+		name      string
+		container string
+		volumes   string
+		// 期望的问题，optional表示问题标记为optional
+		messages []string
+		optional []bool
+		envVar   string
+	}{
+		{
+			name: "引用存在的对象和键",
+			container: `          env:
+            - name: LOG_LEVEL
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: LOG_LEVEL
+            - name: PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: app-secret
+                  key: PASSWORD
+          envFrom:
+            - configMapRef:
+                name: app-config
+`,
+			volumes: `        - name: assets
+          configMap:
+            name: app-config
+            items:
+              - key: logo.png
+                path: logo.png
+`,
+		},
+		{
+			name: "对象不存在",
+			container: `          env:
+            - name: DB_HOST
+              valueFrom:
+                configMapKeyRef:
+                  name: db-config
+                  key: DB_HOST
+`,
+			messages: []string{"ConfigMap default/db-config 不存在"},
+			optional: []bool{false},
+			envVar:   "DB_HOST",
+		},
+		{
+			name: "键不存在",
+			container: `          env:
+            - name: TOKEN
+              valueFrom:
+                secretKeyRef:
+                  name: app-secret
+                  key: TOKEN
+`,
+			messages: []string{"Secret default/app-secret 不包含键 TOKEN"},
+			optional: []bool{false},
+			envVar:   "TOKEN",
+		},
+		{
+			name: "optional引用的对象不存在",
+			container: `          env:
+            - name: DB_HOST
+              valueFrom:
+                configMapKeyRef:
+                  name: db-config
+                  key: DB_HOST
+                  optional: true
+`,
+			messages: []string{"db-config 不存在"},
+			optional: []bool{true},
+			envVar:   "DB_HOST",
+		},
+		{
+			name: "optional引用的键不存在",
+			container: `          env:
+            - name: REGION
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: REGION
+                  optional: true
+`,
+			messages: []string{"不包含键 REGION"},
+			optional: []bool{true},
+			envVar:   "REGION",
+		},
+		{
+			// 环境变量不能引用binaryData中的键
+			name: "env引用binaryData中的键",
+			container: `          env:
+            - name: LOGO
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: logo.png
+`,
+			messages: []string{"不包含键 logo.png"},
+			optional: []bool{false},
+			envVar:   "LOGO",
+		},
+		{
+			name: "其他命名空间中的同名对象",
+			container: `          envFrom:
+            - configMapRef:
+                name: prod-config
+            - secretRef:
+                name: db-secret
+                optional: true
+`,
+			messages: []string{"命名空间 [prod] 中有同名对象", "Secret default/db-secret 不存在"},
+			optional: []bool{false, true},
+		},
+		{
+			name: "卷引用的对象和键不存在",
+			volumes: `        - name: assets
+          configMap:
+            name: app-config
+            items:
+              - key: missing.png
+                path: missing.png
+        - name: certs
+          secret:
+            secretName: tls
+            optional: true
+        - name: bundle
+          projected:
+            sources:
+              - secret:
+                  name: bundle-secret
+`,
+			messages: []string{
+				"configMap卷 引用的ConfigMap default/app-config 不包含键 missing.png",
+				"secret卷 引用的Secret default/tls 不存在",
+				"projected卷 引用的Secret default/bundle-secret 不存在",
+			},
+			optional: []bool{false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProcessor(t, nil)
+			files := loadTestFiles(t, p, map[string]string{
+				"config.yaml": refcheckConfig,
+				"app.yaml":    refcheckWorkload(tt.container, tt.volumes),
+			})
+			if err := p.InitializeCache(context.Background(), files); err != nil {
+				t.Fatal(err)
+			}
+
+			var issues []ReferenceIssue
+			for _, file := range files {
+				for _, document := range file.Documents {
+					issues = append(issues, p.WorkloadProcessor.CheckReferences(document)...)
+				}
+			}
+			if len(issues) != len(tt.messages) {
+				t.Fatalf("问题 = %+v, 期望 %d 个", issues, len(tt.messages))
+			}
+			for i, issue := range issues {
+				if !strings.Contains(issue.Message, tt.messages[i]) || issue.Optional != tt.optional[i] {
+					t.Errorf("问题 = %+v, 期望包含 %q 且optional为 %v", issue, tt.messages[i], tt.optional[i])
+				}
+				if issue.Line == 0 {
+					t.Errorf("问题缺少行号: %+v", issue)
+				}
+				if issue.EnvVar != tt.envVar {
+					t.Errorf("EnvVar = %q, 期望 %q", issue.EnvVar, tt.envVar)
+				}
+			}
+		})
+	}
+}
+
+// lint将缺失的引用报告为错误，optional引用报告为警告，不写入任何文件
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		optional bool
+		severity string
+		exitCode int
+	}{
+		{"缺失的引用", false, utils.SeverityError, utils.ExitErrors},
+		{"optional引用", true, utils.SeverityWarning, utils.ExitClean},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := "                  key: DB_HOST\n"
+			if tt.optional {
+				ref += "                  optional: true\n"
+			}
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.FailOn = utils.FailOnError
+			})
+			writeFiles(t, p.Options.InputDir, map[string]string{
+				"config.yaml": refcheckConfig,
+				"app.yaml": refcheckWorkload(`          env:
+            - name: LOG_LEVEL
+            - name: DB_HOST
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+`+ref, ""),
+			})
+			if err := p.Lint(); err != nil {
+				t.Fatalf("Lint: %v", err)
+			}
+
+			var findings []utils.Finding
+			for _, finding := range p.Report.Findings {
+				if finding.Code == utils.CodeMissingReference {
+					findings = append(findings, finding)
+				}
+			}
+			if len(findings) != 1 || findings[0].Severity != tt.severity || findings[0].EnvVar != "DB_HOST" ||
+				!strings.HasSuffix(findings[0].File, "app.yaml") || findings[0].Line == 0 {
+				t.Fatalf("发现 = %+v, 期望DB_HOST的 %s", findings, tt.severity)
+			}
+			if code := p.ExitCode(); code != tt.exitCode {
+				t.Errorf("ExitCode() = %d, 期望 %d", code, tt.exitCode)
+			}
+			if output := readOutputDir(t, p.Options.OutputDir); len(output) != 0 {
+				t.Errorf("lint不应写入文件: %v", output)
+			}
+		})
+	}
+}
+
+// 处理时引用清单以外的对象只报告警告，示例目录的干运行不以错误退出；lint仍报告为错误
+func TestProcessExamplesMissingReference(t *testing.T) {
+	for _, lint := range []bool{false, true} {
+		p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+			options.InputDir = "../../examples"
+			options.Mode = utils.ModeDryRun
+			options.FailOn = utils.FailOnError
+		})
+
+		run := p.Execute
+		if lint {
+			run = p.Lint
+		}
+		if err := run(); err != nil {
+			t.Fatalf("lint=%v: %v", lint, err)
+		}
+
+		var severity string
+		for _, finding := range p.Report.Findings {
+			if finding.Code == utils.CodeMissingReference && strings.Contains(finding.Message, "existing-secret") {
+				severity = finding.Severity
+			}
+		}
+		want, wantCode := utils.SeverityWarning, utils.ExitClean
+		if lint {
+			want, wantCode = utils.SeverityError, utils.ExitErrors
+		}
+		if severity != want {
+			t.Errorf("lint=%v: existing-secret的缺失引用级别 = %q, 期望 %q", lint, severity, want)
+		}
+		if code := p.ExitCode(); code != wantCode {
+			t.Errorf("lint=%v: 退出码 = %d, 期望 %d", lint, code, wantCode)
+		}
+	}
+}
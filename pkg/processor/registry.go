@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 内置工作负载类型的API组和pod规格路径，匹配组内的任意版本，
+// 其他组中的同名类型（例如CRD）不视为内置工作负载
+var builtinPodSpecPaths = map[string]struct {
+	apiVersion string
+	paths      [][]string
+}{
+	utils.DeploymentKind:            {"apps/*", [][]string{{"spec", "template", "spec"}}},
+	utils.StatefulSetKind:           {"apps/*", [][]string{{"spec", "template", "spec"}}},
+	utils.DaemonSetKind:             {"apps/*", [][]string{{"spec", "template", "spec"}}},
+	utils.ReplicaSetKind:            {"apps/*", [][]string{{"spec", "template", "spec"}}},
+	utils.ReplicationControllerKind: {"v1", [][]string{{"spec", "template", "spec"}}},
+	utils.JobKind:                   {"batch/*", [][]string{{"spec", "template", "spec"}}},
+	utils.CronJobKind:               {"batch/*", [][]string{{"spec", "jobTemplate", "spec", "template", "spec"}}},
+	utils.PodKind:                   {"v1", [][]string{{"spec"}}},
+	utils.PodTemplateKind:           {"v1", [][]string{{"template", "spec"}}},
+}
+
+// 自定义工作负载配置文件
+type WorkloadConfig struct {
+	// 工作负载定义列表
+	Workloads []WorkloadDefinition `yaml:"workloads"`
+}
+
+// 自定义工作负载定义
+type WorkloadDefinition struct {
+	// API版本，为空表示任意版本，group/*表示该组的任意版本
+	APIVersion string `yaml:"apiVersion"`
+	// 资源类型
+	Kind string `yaml:"kind"`
+	// pod规格路径，以.分隔，例如spec.template.spec
+	PodSpecPaths []string `yaml:"podSpecPaths"`
+}
+
+// 注册的pod规格路径
+type podSpecEntry struct {
+	apiVersion string
+	path       []string
+}
+
+// 工作负载注册表：资源类型到pod规格路径的映射
+type WorkloadRegistry struct {
+	// 资源类型 -> pod规格路径列表
+	paths map[string][]podSpecEntry
+	// 是否在未注册的资源中自动查找容器列表
+	Discover bool
+}
+
+// 创建包含内置工作负载类型的注册表
+func NewWorkloadRegistry() *WorkloadRegistry {
+	registry := &WorkloadRegistry{paths: make(map[string][]podSpecEntry)}
+	for kind, builtin := range builtinPodSpecPaths {
+		for _, path := range builtin.paths {
+			registry.Register(builtin.apiVersion, kind, path...)
+		}
+	}
+	return registry
+}
+
+// 注册资源类型的pod规格路径，apiVersion为空时匹配任意版本
+func (r *WorkloadRegistry) Register(apiVersion, kind string, path ...string) {
+	r.paths[kind] = append(r.paths[kind], podSpecEntry{apiVersion: apiVersion, path: path})
+}
+
+// 从配置文件加载自定义工作负载
+func (r *WorkloadRegistry) LoadConfig(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("无法读取工作负载配置 %s: %v", filePath, err)
+	}
+
+	var config WorkloadConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("无法解析工作负载配置 %s: %v", filePath, err)
+	}
+
+	for i, definition := range config.Workloads {
+		if definition.Kind == "" {
+			return fmt.Errorf("工作负载配置 %s 第%d项缺少kind", filePath, i+1)
+		}
+		if len(definition.PodSpecPaths) == 0 {
+			return fmt.Errorf("工作负载配置 %s 中 %s 缺少podSpecPaths", filePath, definition.Kind)
+		}
+
+		for _, path := range definition.PodSpecPaths {
+			if path == "" {
+				return fmt.Errorf("工作负载配置 %s 中 %s 的pod规格路径为空", filePath, definition.Kind)
+			}
+			segments := strings.Split(path, ".")
+			for _, segment := range segments {
+				if segment == "" {
+					return fmt.Errorf("工作负载配置 %s 中 %s 的pod规格路径 %s 无效", filePath, definition.Kind, path)
+				}
+			}
+			r.Register(definition.APIVersion, definition.Kind, segments...)
+		}
+	}
+
+	return nil
+}
+
+// 获取资源类型的所有pod规格路径
+func (r *WorkloadRegistry) PodSpecPaths(apiVersion, kind string) [][]string {
+	var paths [][]string
+	for _, entry := range r.paths[kind] {
+		if matchAPIVersion(entry.apiVersion, apiVersion) {
+			paths = append(paths, entry.path)
+		}
+	}
+	return paths
+}
+
+// 判断是否为已注册的工作负载类型
+func (r *WorkloadRegistry) IsWorkload(apiVersion, kind string) bool {
+	return len(r.PodSpecPaths(apiVersion, kind)) > 0
+}
+
+// 匹配API版本
+func matchAPIVersion(pattern, apiVersion string) bool {
+	if pattern == "" || pattern == apiVersion {
+		return true
+	}
+	if group, found := strings.CutSuffix(pattern, "/*"); found {
+		return strings.HasPrefix(apiVersion, group+"/")
+	}
+	return false
+}
+
+// 在资源中查找所有看起来像pod规格的节点：
+// 包含containers列表，且列表项都具有name和image字段
+func DiscoverPodSpecs(root *yaml.Node) []*yaml.Node {
+	var specs []*yaml.Node
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			if looksLikeContainerList(parser.MappingValue(node, utils.ContainersField)) {
+				specs = append(specs, node)
+				return
+			}
+			for i := 1; i < len(node.Content); i += 2 {
+				walk(node.Content[i])
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item)
+			}
+		}
+	}
+	walk(root)
+
+	return specs
+}
+
+// 判断节点是否为容器列表
+func looksLikeContainerList(node *yaml.Node) bool {
+	if node == nil || node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, item := range node.Content {
+		_, hasName := parser.MappingString(item, "name")
+		_, hasImage := parser.MappingString(item, "image")
+		if !hasName || !hasImage {
+			return false
+		}
+	}
+	return true
+}
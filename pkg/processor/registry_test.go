@@ -0,0 +1,260 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWorkloadRegistryAPIVersion(t *testing.T) {
+	registry := NewWorkloadRegistry()
+	registry.Register("argoproj.io/*", "Rollout", "spec", "template", "spec")
+
+	tests := []struct {
+		apiVersion string
+		kind       string
+		want       bool
+	}{
+		{"apps/v1", utils.DeploymentKind, true},
+		{"apps/v1beta2", utils.StatefulSetKind, true},
+		{"batch/v1", utils.CronJobKind, true},
+		{"v1", utils.PodKind, true},
+		{"v1", utils.ReplicationControllerKind, true},
+		// 其他组中的同名CRD不是内置工作负载
+		{"example.com/v1", utils.DeploymentKind, false},
+		{"workflows.example.com/v1alpha1", utils.JobKind, false},
+		{"serving.knative.dev/v1", utils.PodKind, false},
+		{"", utils.DeploymentKind, false},
+		{"argoproj.io/v1alpha1", "Rollout", true},
+		{"example.com/v1", "Rollout", false},
+	}
+
+	for _, tt := range tests {
+		if got := registry.IsWorkload(tt.apiVersion, tt.kind); got != tt.want {
+			t.Errorf("IsWorkload(%q, %s) = %v, 期望 %v", tt.apiVersion, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAPIVersion(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		apiVersion string
+		want       bool
+	}{
+		{"", "example.com/v1", true},
+		{"v1", "v1", true},
+		{"v1", "apps/v1", false},
+		{"apps/*", "apps/v1", true},
+		{"apps/*", "apps/v1beta1", true},
+		{"apps/*", "apps", false},
+		{"apps/*", "v1", false},
+		{"apps/*", "apps.example.com/v1", false},
+		{"batch/*", "batch/v1", true},
+		{"batch/*", "batchx/v1", false},
+		{"argoproj.io/v1alpha1", "argoproj.io/v1alpha1", true},
+		{"argoproj.io/v1alpha1", "argoproj.io/v1", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchAPIVersion(tt.pattern, tt.apiVersion); got != tt.want {
+			t.Errorf("matchAPIVersion(%q, %q) = %v, 期望 %v", tt.pattern, tt.apiVersion, got, tt.want)
+		}
+	}
+}
+
+func TestLoadWorkloadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		want    map[string][][]string
+	}{
+		{
+			name: "多个路径",
+			content: `workloads:
+  - apiVersion: argoproj.io/*
+    kind: Rollout
+    podSpecPaths: [spec.template.spec]
+  - kind: DeploymentConfig
+    podSpecPaths: [spec.template.spec, spec.strategy.spec]
+`,
+			want: map[string][][]string{
+				"argoproj.io/v1alpha1 Rollout":          {{"spec", "template", "spec"}},
+				"example.com/v1 Rollout":                nil,
+				"apps.openshift.io/v1 DeploymentConfig": {{"spec", "template", "spec"}, {"spec", "strategy", "spec"}},
+			},
+		},
+		{name: "文件不存在", wantErr: "无法读取工作负载配置"},
+		{name: "无效的YAML", content: "workloads: [unclosed\n", wantErr: "无法解析工作负载配置"},
+		{name: "格式不匹配", content: "workloads: {kind: Rollout}\n", wantErr: "无法解析工作负载配置"},
+		{name: "缺少kind", content: "workloads:\n  - podSpecPaths: [spec]\n", wantErr: "第1项缺少kind"},
+		{name: "缺少路径", content: "workloads:\n  - kind: Rollout\n", wantErr: "Rollout 缺少podSpecPaths"},
+		{name: "空路径", content: "workloads:\n  - kind: Rollout\n    podSpecPaths: [\"\"]\n", wantErr: "pod规格路径为空"},
+		{name: "空的路径段", content: "workloads:\n  - kind: Rollout\n    podSpecPaths: [spec..spec]\n", wantErr: "pod规格路径 spec..spec 无效"},
+		{name: "以点结尾", content: "workloads:\n  - kind: Rollout\n    podSpecPaths: [spec.template.]\n", wantErr: "无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "workloads.yaml")
+			if tt.content != "" {
+				if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			registry := NewWorkloadRegistry()
+			err := registry.LoadConfig(filePath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() = %v, 期望包含 %q 的错误", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			for resource, want := range tt.want {
+				apiVersion, kind, _ := strings.Cut(resource, " ")
+				if got := registry.PodSpecPaths(apiVersion, kind); !reflect.DeepEqual(got, want) {
+					t.Errorf("PodSpecPaths(%q, %s) = %v, 期望 %v", apiVersion, kind, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoverPodSpecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// 找到的pod规格中第一个容器的名称
+		want []string
+	}{
+		{
+			name: "嵌套的pod规格",
+			content: `spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: app
+`,
+			want: []string{"app"},
+		},
+		{
+			name: "列表中的多个pod规格",
+			content: `spec:
+  components:
+    - spec:
+        containers:
+          - name: first
+            image: first
+    - spec:
+        containers:
+          - name: second
+            image: second
+`,
+			want: []string{"first", "second"},
+		},
+		{
+			name: "缺少image",
+			content: `spec:
+  containers:
+    - name: app
+`,
+		},
+		{
+			name: "部分容器缺少name",
+			content: `spec:
+  containers:
+    - name: app
+      image: app
+    - image: sidecar
+`,
+		},
+		{name: "空列表", content: "spec:\n  containers: []\n"},
+		{name: "不是列表", content: "spec:\n  containers: app\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var document yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.content), &document); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, spec := range DiscoverPodSpecs(document.Content[0]) {
+				containers := parser.MappingValue(spec, utils.ContainersField)
+				name, _ := parser.MappingString(containers.Content[0], "name")
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverPodSpecs() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 与内置类型同名的CRD保持不变，也不报告缺少pod规格
+func TestProcessSameKindCRD(t *testing.T) {
+	p, _ := newTestProcessor(t, nil)
+	files := loadTestFiles(t, p, map[string]string{
+		"config.yaml": testConfigMap,
+		"crd.yaml": `apiVersion: example.com/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+`,
+	})
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		t.Fatal(err)
+	}
+	p.ProcessFiles(files)
+
+	if outputs := readOutputDir(t, p.Options.OutputDir); len(outputs) != 0 {
+		t.Errorf("不应修改CRD: %v", outputs)
+	}
+	if p.Report.SuccessfulUpdates != 0 || len(p.Report.Findings) != 0 {
+		t.Errorf("不应处理CRD: %+v", p.Report.Findings)
+	}
+}
+
+// 示例中的工作负载配置可以加载，且不会被当作清单扫描
+func TestExampleWorkloadConfig(t *testing.T) {
+	if err := NewWorkloadRegistry().LoadConfig("../../examples/config/workloads.yaml"); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.InputDir = "../../examples"
+	})
+	scanned, err := p.Parser.ScanDirectory(p.Options.InputDir, p.scanOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range scanned {
+		if strings.HasSuffix(path, "workloads.yaml") {
+			t.Errorf("工作负载配置不应被当作清单扫描: %s", path)
+		}
+	}
+	if len(scanned) == 0 {
+		t.Error("示例目录中应扫描到清单")
+	}
+}
@@ -0,0 +1,264 @@
+package processor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 配置查找请求
+type ResolveRequest struct {
+	// 环境变量名
+	EnvName string
+	// 命名空间
+	Namespace string
+	// 所属工作负载名称
+	Workload string
+}
+
+// 配置查找结果
+type Resolution struct {
+	// 配置值
+	Value string
+	// 配置对象名称
+	ConfigName string
+	// 配置对象类型: ConfigMap或Secret
+	ConfigKind string
+	// 配置对象中的键，通常与环境变量名相同
+	Key string
+	// 命中的解析策略
+	Strategy string
+}
+
+// 名称解析策略
+type Resolver interface {
+	// 策略名称
+	Name() string
+	// 返回所有满足条件的候选配置，按优先级排序
+	Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution
+}
+
+// 按优先级依次尝试的解析策略链
+type ResolverChain struct {
+	// 解析策略，按优先级排列
+	Resolvers []Resolver
+	// 配置缓存
+	ConfigCache *utils.ConfigCache
+}
+
+// 根据策略名称创建解析策略链
+func NewResolverChain(names []string, prefixRules map[string]string, cache *utils.ConfigCache) (*ResolverChain, error) {
+	chain := &ResolverChain{ConfigCache: cache}
+
+	for _, name := range names {
+		resolver, err := NewResolver(name, prefixRules)
+		if err != nil {
+			return nil, err
+		}
+		chain.Resolvers = append(chain.Resolvers, resolver)
+	}
+
+	return chain, nil
+}
+
+// 根据名称创建内置的解析策略，prefixRules只用于前缀规则策略
+func NewResolver(name string, prefixRules map[string]string) (Resolver, error) {
+	switch name {
+	case utils.ResolverExact:
+		return exactResolver{}, nil
+	case utils.ResolverWorkload:
+		return workloadResolver{}, nil
+	case utils.ResolverPrefix:
+		return newPrefixResolver(prefixRules), nil
+	case utils.ResolverKey:
+		return keyResolver{}, nil
+	default:
+		return nil, fmt.Errorf("未知的解析策略: %s", name)
+	}
+}
+
+// 按优先级查找配置，返回第一个命中策略的首选结果及该策略的全部候选
+func (c *ResolverChain) Resolve(request ResolveRequest) (Resolution, []Resolution, bool) {
+	for _, resolver := range c.Resolvers {
+		candidates := resolver.Candidates(request, c.ConfigCache)
+		if len(candidates) > 0 {
+			return candidates[0], candidates, true
+		}
+	}
+
+	return Resolution{}, nil, false
+}
+
+// 根据环境变量名生成配置对象名称（小写并替换下划线为中划线）
+func ConfigNameForEnv(envName string) string {
+	return strings.ToLower(strings.ReplaceAll(envName, "_", "-"))
+}
+
+// 精确命名约定：配置对象名称等于环境变量名的小写形式
+type exactResolver struct{}
+
+func (exactResolver) Name() string {
+	return utils.ResolverExact
+}
+
+func (exactResolver) Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution {
+	return lookupNamed(cache, request, utils.ResolverExact, ConfigNameForEnv(request.EnvName), request.EnvName)
+}
+
+// 工作负载命名约定：<工作负载>-config的ConfigMap和<工作负载>-secret的Secret
+type workloadResolver struct{}
+
+func (workloadResolver) Name() string {
+	return utils.ResolverWorkload
+}
+
+func (workloadResolver) Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution {
+	if request.Workload == "" {
+		return nil
+	}
+
+	var candidates []Resolution
+	if value, exists := lookupKey(cache.ConfigMaps, request.Namespace, request.Workload+"-config", request.EnvName); exists {
+		candidates = append(candidates, Resolution{
+			Value:      value,
+			ConfigName: request.Workload + "-config",
+			ConfigKind: utils.ConfigMapKind,
+			Key:        request.EnvName,
+			Strategy:   utils.ResolverWorkload,
+		})
+	}
+	if value, exists := lookupKey(cache.Secrets, request.Namespace, request.Workload+"-secret", request.EnvName); exists {
+		candidates = append(candidates, Resolution{
+			Value:      value,
+			ConfigName: request.Workload + "-secret",
+			ConfigKind: utils.SecretKind,
+			Key:        request.EnvName,
+			Strategy:   utils.ResolverWorkload,
+		})
+	}
+
+	return candidates
+}
+
+// 前缀规则：例如DB_*映射到db-config
+type prefixResolver struct {
+	// 按前缀长度降序排列的规则
+	prefixes []string
+	rules    map[string]string
+}
+
+func newPrefixResolver(rules map[string]string) prefixResolver {
+	prefixes := make([]string, 0, len(rules))
+	for prefix := range rules {
+		prefixes = append(prefixes, prefix)
+	}
+
+	// 最长前缀优先，长度相同时按字典序
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+
+	return prefixResolver{prefixes: prefixes, rules: rules}
+}
+
+func (prefixResolver) Name() string {
+	return utils.ResolverPrefix
+}
+
+func (r prefixResolver) Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution {
+	for _, prefix := range r.prefixes {
+		if !strings.HasPrefix(request.EnvName, prefix) {
+			continue
+		}
+		// 先查找完整的环境变量名，再查找去掉前缀后的键（DB_HOST -> HOST）
+		if candidates := lookupNamed(cache, request, utils.ResolverPrefix, r.rules[prefix], request.EnvName); len(candidates) > 0 {
+			return candidates
+		}
+		if key := strings.TrimPrefix(request.EnvName, prefix); key != "" {
+			if candidates := lookupNamed(cache, request, utils.ResolverPrefix, r.rules[prefix], key); len(candidates) > 0 {
+				return candidates
+			}
+		}
+	}
+
+	return nil
+}
+
+// 键匹配：命名空间内任意包含该键的ConfigMap或Secret
+type keyResolver struct{}
+
+func (keyResolver) Name() string {
+	return utils.ResolverKey
+}
+
+func (keyResolver) Candidates(request ResolveRequest, cache *utils.ConfigCache) []Resolution {
+	var candidates []Resolution
+
+	for _, name := range sortedKeys(cache.ConfigMaps[request.Namespace]) {
+		if value, exists := cache.ConfigMaps[request.Namespace][name][request.EnvName]; exists {
+			candidates = append(candidates, Resolution{
+				Value:      value,
+				ConfigName: name,
+				ConfigKind: utils.ConfigMapKind,
+				Key:        request.EnvName,
+				Strategy:   utils.ResolverKey,
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(cache.Secrets[request.Namespace]) {
+		if value, exists := cache.Secrets[request.Namespace][name][request.EnvName]; exists {
+			candidates = append(candidates, Resolution{
+				Value:      value,
+				ConfigName: name,
+				ConfigKind: utils.SecretKind,
+				Key:        request.EnvName,
+				Strategy:   utils.ResolverKey,
+			})
+		}
+	}
+
+	return candidates
+}
+
+// 在同名的ConfigMap和Secret中查找键，ConfigMap优先
+func lookupNamed(cache *utils.ConfigCache, request ResolveRequest, strategy, configName, key string) []Resolution {
+	var candidates []Resolution
+
+	if value, exists := lookupKey(cache.ConfigMaps, request.Namespace, configName, key); exists {
+		candidates = append(candidates, Resolution{
+			Value:      value,
+			ConfigName: configName,
+			ConfigKind: utils.ConfigMapKind,
+			Key:        key,
+			Strategy:   strategy,
+		})
+	}
+	if value, exists := lookupKey(cache.Secrets, request.Namespace, configName, key); exists {
+		candidates = append(candidates, Resolution{
+			Value:      value,
+			ConfigName: configName,
+			ConfigKind: utils.SecretKind,
+			Key:        key,
+			Strategy:   strategy,
+		})
+	}
+
+	return candidates
+}
+
+// 在缓存中查找指定配置对象的键
+func lookupKey(objects map[string]map[string]map[string]string, namespace, name, key string) (string, bool) {
+	if namespaceObjects, exists := objects[namespace]; exists {
+		if data, exists := namespaceObjects[name]; exists {
+			value, exists := data[key]
+			return value, exists
+		}
+	}
+	return "", false
+}
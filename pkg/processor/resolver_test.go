@@ -0,0 +1,266 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 创建default命名空间中包含给定ConfigMap和Secret的缓存
+func newResolverCache(configMaps, secrets map[string]map[string]string) *utils.ConfigCache {
+	cache := utils.NewConfigCache()
+	cache.ConfigMaps[utils.DefaultNamespace] = configMaps
+	cache.Secrets[utils.DefaultNamespace] = secrets
+	return cache
+}
+
+func TestResolverChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolvers  []string
+		rules      map[string]string
+		configMaps map[string]map[string]string
+		secrets    map[string]map[string]string
+		envName    string
+		// 期望的结果，strategy为空表示未找到
+		strategy   string
+		kind       string
+		configName string
+		key        string
+		candidates int
+	}{
+		{
+			name:      "exact优先于workload",
+			resolvers: []string{utils.ResolverExact, utils.ResolverWorkload},
+			configMaps: map[string]map[string]string{
+				"db-host":    {"DB_HOST": "exact"},
+				"web-config": {"DB_HOST": "workload"},
+			},
+			envName:  "DB_HOST",
+			strategy: utils.ResolverExact, kind: utils.ConfigMapKind, configName: "db-host", key: "DB_HOST", candidates: 1,
+		},
+		{
+			name:      "按指定顺序workload优先",
+			resolvers: []string{utils.ResolverWorkload, utils.ResolverExact},
+			configMaps: map[string]map[string]string{
+				"db-host":    {"DB_HOST": "exact"},
+				"web-config": {"DB_HOST": "workload"},
+			},
+			envName:  "DB_HOST",
+			strategy: utils.ResolverWorkload, kind: utils.ConfigMapKind, configName: "web-config", key: "DB_HOST", candidates: 1,
+		},
+		{
+			name:       "exact中ConfigMap优先于同名Secret",
+			resolvers:  []string{utils.ResolverExact},
+			configMaps: map[string]map[string]string{"api-token": {"API_TOKEN": "a"}},
+			secrets:    map[string]map[string]string{"api-token": {"API_TOKEN": "b"}},
+			envName:    "API_TOKEN",
+			strategy:   utils.ResolverExact, kind: utils.ConfigMapKind, configName: "api-token", key: "API_TOKEN", candidates: 2,
+		},
+		{
+			name:      "workload查找Secret",
+			resolvers: []string{utils.ResolverWorkload},
+			secrets:   map[string]map[string]string{"web-secret": {"DB_PASSWORD": "s3cret"}},
+			envName:   "DB_PASSWORD",
+			strategy:  utils.ResolverWorkload, kind: utils.SecretKind, configName: "web-secret", key: "DB_PASSWORD", candidates: 1,
+		},
+		{
+			name:       "exact未命中时使用prefix",
+			resolvers:  []string{utils.ResolverExact, utils.ResolverPrefix, utils.ResolverKey},
+			rules:      map[string]string{"DB_": "db-config"},
+			configMaps: map[string]map[string]string{"db-config": {"DB_HOST": "db"}, "other": {"DB_HOST": "other"}},
+			envName:    "DB_HOST",
+			strategy:   utils.ResolverPrefix, kind: utils.ConfigMapKind, configName: "db-config", key: "DB_HOST", candidates: 1,
+		},
+		{
+			name:       "prefix去掉前缀查找",
+			resolvers:  []string{utils.ResolverPrefix},
+			rules:      map[string]string{"DB_": "db-config"},
+			configMaps: map[string]map[string]string{"db-config": {"HOST": "db"}},
+			envName:    "DB_HOST",
+			strategy:   utils.ResolverPrefix, kind: utils.ConfigMapKind, configName: "db-config", key: "HOST", candidates: 1,
+		},
+		{
+			name:       "prefix完整名称优先于去掉前缀的键",
+			resolvers:  []string{utils.ResolverPrefix},
+			rules:      map[string]string{"DB_": "db-config"},
+			configMaps: map[string]map[string]string{"db-config": {"HOST": "short", "DB_HOST": "full"}},
+			envName:    "DB_HOST",
+			strategy:   utils.ResolverPrefix, kind: utils.ConfigMapKind, configName: "db-config", key: "DB_HOST", candidates: 1,
+		},
+		{
+			name:      "最长前缀优先",
+			resolvers: []string{utils.ResolverPrefix},
+			rules:     map[string]string{"DB_": "db-config", "DB_READ_": "replica-config"},
+			configMaps: map[string]map[string]string{
+				"db-config":      {"DB_READ_HOST": "primary"},
+				"replica-config": {"HOST": "replica"},
+			},
+			envName:  "DB_READ_HOST",
+			strategy: utils.ResolverPrefix, kind: utils.ConfigMapKind, configName: "replica-config", key: "HOST", candidates: 1,
+		},
+		{
+			name:      "最长前缀未命中时使用较短的前缀",
+			resolvers: []string{utils.ResolverPrefix},
+			rules:     map[string]string{"DB_": "db-config", "DB_READ_": "replica-config"},
+			configMaps: map[string]map[string]string{
+				"db-config":      {"READ_PORT": "5432"},
+				"replica-config": {"HOST": "replica"},
+			},
+			envName:  "DB_READ_PORT",
+			strategy: utils.ResolverPrefix, kind: utils.ConfigMapKind, configName: "db-config", key: "READ_PORT", candidates: 1,
+		},
+		{
+			name:       "名称与前缀相同时不查找空键",
+			resolvers:  []string{utils.ResolverPrefix},
+			rules:      map[string]string{"DB_": "db-config"},
+			configMaps: map[string]map[string]string{"db-config": {"": "empty"}},
+			envName:    "DB_",
+		},
+		{
+			name:      "key按名称排序且ConfigMap优先",
+			resolvers: []string{utils.ResolverKey},
+			configMaps: map[string]map[string]string{
+				"zeta":  {"REGION": "z"},
+				"alpha": {"REGION": "a"},
+			},
+			secrets:  map[string]map[string]string{"aaa": {"REGION": "s"}},
+			envName:  "REGION",
+			strategy: utils.ResolverKey, kind: utils.ConfigMapKind, configName: "alpha", key: "REGION", candidates: 3,
+		},
+		{
+			name:      "都未命中",
+			resolvers: utils.DefaultResolvers,
+			rules:     map[string]string{"DB_": "db-config"},
+			configMaps: map[string]map[string]string{
+				"db-config": {"PORT": "5432"},
+			},
+			envName: "DB_HOST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newResolverCache(tt.configMaps, tt.secrets)
+			chain, err := NewResolverChain(tt.resolvers, tt.rules, cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resolution, candidates, found := chain.Resolve(ResolveRequest{
+				EnvName:   tt.envName,
+				Namespace: utils.DefaultNamespace,
+				Workload:  "web",
+			})
+			if found != (tt.strategy != "") {
+				t.Fatalf("found = %v, 结果 %+v", found, resolution)
+			}
+			if !found {
+				return
+			}
+			if resolution.Strategy != tt.strategy || resolution.ConfigKind != tt.kind ||
+				resolution.ConfigName != tt.configName || resolution.Key != tt.key {
+				t.Errorf("Resolve() = %+v, 期望 %s %s/%s 键 %s", resolution, tt.strategy, tt.kind, tt.configName, tt.key)
+			}
+			if resolution.Value != cacheObjects(cache, tt.kind)[utils.DefaultNamespace][tt.configName][tt.key] {
+				t.Errorf("值 = %q, 与缓存不一致", resolution.Value)
+			}
+			if len(candidates) != tt.candidates {
+				t.Errorf("候选数 = %d, 期望 %d: %+v", len(candidates), tt.candidates, candidates)
+			}
+		})
+	}
+}
+
+func TestResolverNamespaceAndErrors(t *testing.T) {
+	cache := utils.NewConfigCache()
+	cache.ConfigMaps["prod"] = map[string]map[string]string{"log-level": {"LOG_LEVEL": "warn"}}
+
+	chain, err := NewResolverChain(utils.DefaultResolvers, nil, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, found := chain.Resolve(ResolveRequest{EnvName: "LOG_LEVEL", Namespace: utils.DefaultNamespace, Workload: "web"}); found {
+		t.Error("不应解析到其他命名空间中的配置")
+	}
+	if resolution, _, found := chain.Resolve(ResolveRequest{EnvName: "LOG_LEVEL", Namespace: "prod"}); !found || resolution.Value != "warn" {
+		t.Errorf("Resolve(prod) = %+v, %v", resolution, found)
+	}
+
+	// 没有工作负载名称时workload策略不命中
+	cache.ConfigMaps["prod"]["-config"] = map[string]string{"PORT": "80"}
+	workloadChain, err := NewResolverChain([]string{utils.ResolverWorkload}, nil, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, found := workloadChain.Resolve(ResolveRequest{EnvName: "PORT", Namespace: "prod"}); found {
+		t.Error("没有工作负载名称时workload策略不应命中")
+	}
+
+	if _, err := NewResolverChain([]string{utils.ResolverExact, "fuzzy"}, nil, cache); err == nil {
+		t.Error("未知的解析策略应返回错误")
+	}
+}
+
+// 命中的策略有多个候选时报告歧义，并使用第一个候选
+func TestResolveAmbiguous(t *testing.T) {
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.Resolvers = []string{utils.ResolverExact, utils.ResolverKey}
+	})
+	files := loadTestFiles(t, p, map[string]string{
+		"config.yaml": testConfigMap + `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: zone-a
+data:
+  REGION: eu
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: zone-b
+data:
+  REGION: us
+`,
+		"app.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+            - name: REGION
+`,
+	})
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		t.Fatal(err)
+	}
+	p.ProcessFiles(files)
+
+	var ambiguous []utils.Finding
+	for _, finding := range p.Report.Findings {
+		if finding.Code == utils.CodeEnvAmbiguous {
+			ambiguous = append(ambiguous, finding)
+		}
+	}
+	// LOG_LEVEL只由exact策略的app-config提供，key策略中的同名键不参与
+	if len(ambiguous) != 1 || ambiguous[0].EnvVar != "REGION" || ambiguous[0].Severity != utils.SeverityWarning {
+		t.Fatalf("歧义警告 = %+v, 期望只有REGION", ambiguous)
+	}
+	if !strings.Contains(ambiguous[0].Message, "ConfigMap/zone-a, ConfigMap/zone-b") ||
+		!strings.Contains(ambiguous[0].Message, "已使用 ConfigMap/zone-a") {
+		t.Errorf("歧义警告应列出候选并说明使用了zone-a: %s", ambiguous[0].Message)
+	}
+
+	output := readOutputDir(t, p.Options.OutputDir)["app.yaml"]
+	if !strings.Contains(output, "name: zone-a") || strings.Contains(output, "zone-b") {
+		t.Errorf("应引用第一个候选zone-a:\n%s", output)
+	}
+}
@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/secrets"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 扫描清单中硬编码的密钥：env的字面值、ConfigMap的data和所有注解，不修改任何文件
+func (p *MainProcessor) Scan() error {
+	files, err := p.loadInput()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		for _, document := range file.Documents {
+			if document.Root() == nil {
+				continue
+			}
+
+			if document.Resource.Kind == utils.ConfigMapKind {
+				p.scanConfigMap(document)
+			}
+			p.scanEnv(document)
+			p.scanAnnotations(document, document.Root())
+		}
+	}
+
+	p.Report.Sort()
+	return p.PrintReport()
+}
+
+// 已知格式和名称匹配的可信度较高，作为错误；只有熵较高的作为警告
+func detectionSeverity(detection secrets.Detection) string {
+	if detection.Rule == secrets.RuleEntropy {
+		return utils.SeverityWarning
+	}
+	return utils.SeverityError
+}
+
+// 检查容器中env的字面值
+func (p *MainProcessor) scanEnv(document *parser.Document) {
+	resource := &document.Resource
+
+	for _, spec := range p.WorkloadProcessor.PodSpecs(document) {
+		forEachContainer(spec, func(listName string, container *yaml.Node) {
+			env := parser.MappingValue(container, "env")
+			if env == nil || env.Kind != yaml.SequenceNode {
+				return
+			}
+
+			containerName, _ := parser.MappingString(container, "name")
+			location := ContainerLocation{
+				Namespace: resource.Metadata.Namespace,
+				Workload:  resource.Metadata.Name,
+				List:      listName,
+				Container: containerName,
+				document:  document,
+			}
+
+			for _, envVar := range env.Content {
+				name, _ := parser.MappingString(envVar, "name")
+				valueNode := parser.MappingValue(envVar, "value")
+				if valueNode == nil || valueNode.Kind != yaml.ScalarNode {
+					continue
+				}
+
+				detection, found := secrets.Detect(name, valueNode.Value, p.Options.SecretPatterns)
+				if !found {
+					continue
+				}
+
+				finding := location.finding(detectionSeverity(detection), utils.CodeHardcodedSecret, valueNode, name)
+				finding.Message = fmt.Sprintf("环境变量 %s 的值 %s 像硬编码的密钥（%s）(%s)",
+					name, secrets.Mask(valueNode.Value), detection.Detail, location)
+				finding.Suggestion = "将值移至Secret并使用secretKeyRef引用，例如运行externalize命令"
+				p.Report.Add(finding)
+			}
+		})
+	}
+}
+
+// 检查ConfigMap中的键和值：名称敏感的键应存放在Secret中，值本身也不能像密钥
+func (p *MainProcessor) scanConfigMap(document *parser.Document) {
+	resource := &document.Resource
+	data := parser.MappingValue(document.Root(), utils.DataField)
+	if data == nil || data.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(data.Content); i += 2 {
+		key, valueNode := data.Content[i].Value, data.Content[i+1]
+		if valueNode.Kind != yaml.ScalarNode {
+			continue
+		}
+		value := valueNode.Value
+
+		finding := utils.Finding{
+			File:      document.Path,
+			Line:      document.Line(valueNode),
+			Namespace: resource.Metadata.Namespace,
+			Workload:  resource.Metadata.Name,
+			EnvVar:    key,
+		}
+
+		if !secrets.IsPlaceholder(value) && secrets.MatchesName(key, p.Options.SecretPatterns) {
+			finding.Severity = utils.SeverityError
+			finding.Code = utils.CodeSecretInConfigMap
+			finding.Message = fmt.Sprintf("ConfigMap %s/%s 中的键 %s 像敏感信息，应存放在Secret中",
+				resource.Metadata.Namespace, resource.Metadata.Name, key)
+			finding.Suggestion = "将该键移至Secret，并把引用改为secretKeyRef"
+			p.Report.Add(finding)
+			continue
+		}
+
+		detection, found := secrets.Detect("", value, p.Options.SecretPatterns)
+		if !found {
+			continue
+		}
+		finding.Severity = detectionSeverity(detection)
+		finding.Code = utils.CodeHardcodedSecret
+		finding.Message = fmt.Sprintf("ConfigMap %s/%s 中键 %s 的值 %s 像硬编码的密钥（%s）",
+			resource.Metadata.Namespace, resource.Metadata.Name, key, secrets.Mask(value), detection.Detail)
+		finding.Suggestion = "将该键移至Secret，并把引用改为secretKeyRef"
+		p.Report.Add(finding)
+	}
+}
+
+// 递归检查节点树中所有metadata.annotations，包括pod模板上的注解
+func (p *MainProcessor) scanAnnotations(document *parser.Document, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "metadata" {
+				p.scanAnnotationMap(document, parser.MappingValue(node.Content[i+1], "annotations"))
+			}
+			p.scanAnnotations(document, node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			p.scanAnnotations(document, item)
+		}
+	}
+}
+
+// 检查一组注解，跳过本工具和kubectl写入的注解
+func (p *MainProcessor) scanAnnotationMap(document *parser.Document, annotations *yaml.Node) {
+	if annotations == nil || annotations.Kind != yaml.MappingNode {
+		return
+	}
+	resource := &document.Resource
+
+	for i := 0; i+1 < len(annotations.Content); i += 2 {
+		name, valueNode := annotations.Content[i].Value, annotations.Content[i+1]
+		if valueNode.Kind != yaml.ScalarNode || name == utils.LastAppliedAnnotation ||
+			name == utils.BaseNameAnnotation || name == utils.ChecksumOwnerAnnotation || strings.HasPrefix(name, utils.ChecksumAnnotationPrefix) {
+			continue
+		}
+
+		detection, found := secrets.Detect(name, valueNode.Value, p.Options.SecretPatterns)
+		if !found {
+			continue
+		}
+		p.Report.Add(utils.Finding{
+			Severity:  detectionSeverity(detection),
+			Code:      utils.CodeHardcodedSecret,
+			File:      document.Path,
+			Line:      document.Line(valueNode),
+			Namespace: resource.Metadata.Namespace,
+			Workload:  resource.Metadata.Name,
+			Message: fmt.Sprintf("%s %s/%s 的注解 %s 的值 %s 像硬编码的密钥（%s）",
+				resource.Kind, resource.Metadata.Namespace, resource.Metadata.Name, name, secrets.Mask(valueNode.Value), detection.Detail),
+			Suggestion: "不要在注解中保存凭据，改为存放在Secret中",
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/k8sconfig-processor/pkg/cluster"
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"k8s.io/client-go/kubernetes"
+)
+
+// 配置来源：提供用于查找环境变量的ConfigMap和Secret
+type Source interface {
+	// 来源名称，用于报告和错误信息
+	Name() string
+	// 读取来源中的ConfigMap和Secret
+	Load(ctx context.Context) ([]utils.KubeResource, error)
+}
+
+// 带优先级的配置来源，同名对象以优先级高的来源为准
+type PrioritySource struct {
+	Source   Source
+	Priority int
+}
+
+// 读取配置来源失败
+type SourceError struct {
+	// 来源名称
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("读取配置来源 %s 失败: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// 内存中的多文档YAML
+type yamlSource struct {
+	name string
+	data []byte
+}
+
+// 创建内存中YAML内容的配置来源
+func NewYAMLSource(name string, data []byte) Source {
+	return &yamlSource{name: name, data: data}
+}
+
+func (s *yamlSource) Name() string {
+	return s.name
+}
+
+func (s *yamlSource) Load(ctx context.Context) ([]utils.KubeResource, error) {
+	return parseResources(s.name, s.data)
+}
+
+// 目录或单个YAML文件
+type directorySource struct {
+	path string
+}
+
+// 创建目录或文件的配置来源，目录按扫描规则递归读取YAML文件
+func NewDirectorySource(path string) Source {
+	return &directorySource{path: path}
+}
+
+func (s *directorySource) Name() string {
+	return s.path
+}
+
+func (s *directorySource) Load(ctx context.Context) ([]utils.KubeResource, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{s.path}
+	if info.IsDir() {
+		scanner := parser.NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+		paths, err = scanner.ScanDirectory(s.path, parser.ScanOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var resources []utils.KubeResource
+	for _, filePath := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := parseResources(filePath, data)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, loaded...)
+	}
+
+	return resources, nil
+}
+
+// 已构造好的资源
+type staticSource struct {
+	name      string
+	resources []utils.KubeResource
+}
+
+// 创建直接提供资源的配置来源
+func NewStaticSource(name string, resources []utils.KubeResource) Source {
+	return &staticSource{name: name, resources: resources}
+}
+
+func (s *staticSource) Name() string {
+	return s.name
+}
+
+func (s *staticSource) Load(ctx context.Context) ([]utils.KubeResource, error) {
+	return s.resources, nil
+}
+
+// 集群中的ConfigMap和Secret，只使用list权限
+type clusterSource struct {
+	name       string
+	client     kubernetes.Interface
+	namespaces []string
+	keysOnly   bool
+}
+
+// 创建集群配置来源，namespaces为空时读取default命名空间
+func NewClusterSource(client kubernetes.Interface, namespaces []string, keysOnly bool) Source {
+	if len(namespaces) == 0 {
+		namespaces = []string{utils.DefaultNamespace}
+	}
+	return &clusterSource{name: "cluster", client: client, namespaces: namespaces, keysOnly: keysOnly}
+}
+
+func (s *clusterSource) Name() string {
+	return s.name
+}
+
+func (s *clusterSource) Load(ctx context.Context) ([]utils.KubeResource, error) {
+	return cluster.LoadResources(ctx, s.client, s.namespaces, s.keysOnly)
+}
+
+// 解析YAML内容中的资源，存在无法解析的文档时返回错误
+func parseResources(name string, data []byte) ([]utils.KubeResource, error) {
+	report := utils.NewProcessReport()
+	file := parser.NewYAMLParser(utils.NewConfigCache(), report).ParseContent(name, data)
+	if file.HasErrors() {
+		return nil, errors.New(report.Errors[0])
+	}
+
+	var resources []utils.KubeResource
+	for _, document := range file.Documents {
+		if document.Root() != nil {
+			resources = append(resources, document.Resource)
+		}
+	}
+	return resources, nil
+}
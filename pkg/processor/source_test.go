@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// 注入fake clientset读取集群中的配置，仓库中的同名对象优先
+func TestClusterSourcePriority(t *testing.T) {
+	client := fake.NewClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"},
+			Data:       map[string]string{"LOG_LEVEL": "cluster", "CLUSTER_ONLY": "1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-config"},
+			Data:       map[string]string{"REGION": "eu"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "prod-config"},
+			Data:       map[string]string{"REPLICAS": "3"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db-secret"},
+			Data:       map[string][]byte{"PASSWORD": []byte("s3cret")},
+		},
+	)
+
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.FromCluster = true
+		options.ClusterNamespaces = []string{"default", "prod"}
+	})
+	p.ClusterClient = client
+
+	files := loadTestFiles(t, p, map[string]string{"config.yaml": testConfigMap})
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		t.Fatalf("InitializeCache: %v", err)
+	}
+
+	// 仓库中的app-config整体覆盖集群中的同名对象，集群中独有的键不保留
+	appConfig := p.ConfigCache.ConfigMaps["default"]["app-config"]
+	if appConfig["LOG_LEVEL"] != "info" {
+		t.Errorf("LOG_LEVEL = %q, 仓库中的值应优先", appConfig["LOG_LEVEL"])
+	}
+	if _, exists := appConfig["CLUSTER_ONLY"]; exists {
+		t.Error("被覆盖的集群对象中的键不应保留")
+	}
+
+	// 只存在于集群中的对象被加入缓存
+	if p.ConfigCache.ConfigMaps["default"]["cluster-config"]["REGION"] != "eu" {
+		t.Error("缺少集群中的cluster-config")
+	}
+	if p.ConfigCache.ConfigMaps["prod"]["prod-config"]["REPLICAS"] != "3" {
+		t.Error("缺少prod命名空间中的prod-config")
+	}
+	if p.ConfigCache.Secrets["default"]["db-secret"]["PASSWORD"] != "s3cret" {
+		t.Error("缺少集群中的db-secret")
+	}
+
+	if log := p.Log.(*bytes.Buffer).String(); !strings.Contains(log, "配置来源 cluster") {
+		t.Errorf("日志中缺少集群来源:\n%s", log)
+	}
+}
+
+// 优先级相同时，--config-source中的文件优先于集群
+func TestClusterSourceSamePriority(t *testing.T) {
+	client := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "shared"},
+		Data:       map[string]string{"VALUE": "cluster"},
+	})
+
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.FromCluster = true
+		options.ClusterNamespaces = []string{"default"}
+	})
+	p.ClusterClient = client
+
+	sourceFiles := loadTestFiles(t, p, map[string]string{
+		"shared.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: shared\ndata:\n  VALUE: file\n",
+	})
+	p.Options.ConfigSources = []utils.ConfigSource{{Path: sourceFiles[0].Path, Priority: utils.DefaultSourcePriority}}
+
+	// 输入中没有文件，配置只来自来源和集群
+	if err := p.InitializeCache(context.Background(), nil); err != nil {
+		t.Fatalf("InitializeCache: %v", err)
+	}
+	if got := p.ConfigCache.ConfigMaps["default"]["shared"]["VALUE"]; got != "file" {
+		t.Errorf("VALUE = %q, 优先级相同时文件应优先于集群", got)
+	}
+}
+
+// 优先级高于输入的--config-source整体替换同名对象：数据、键的来源字段和只有键的标记都以高优先级的对象为准
+func TestConfigSourceOverridesInput(t *testing.T) {
+	client := fake.NewClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db-secret"},
+		Data:       map[string][]byte{"PASSWORD": []byte("cluster"), "CLUSTER_ONLY": []byte("1")},
+	})
+
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.FromCluster = true
+		options.ClusterNamespaces = []string{"default"}
+		options.SecretKeysOnly = true
+	})
+	p.ClusterClient = client
+
+	sourceDir := t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"shared.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  REGION: eu
+binaryData:
+  LOGO: aGVsbG8=
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db-secret
+stringData:
+  PASSWORD: shared
+`})
+	p.Options.ConfigSources = []utils.ConfigSource{{Path: sourceDir, Priority: 200}}
+
+	files := loadTestFiles(t, p, map[string]string{"config.yaml": testConfigMap})
+	if err := p.InitializeCache(context.Background(), files); err != nil {
+		t.Fatalf("InitializeCache: %v", err)
+	}
+	cache := p.ConfigCache
+
+	// 输入中的app-config被整体替换，键的来源字段只保留来源中的键
+	if got := cache.ConfigMaps["default"]["app-config"]; len(got) != 1 || got["REGION"] != "eu" {
+		t.Errorf("app-config = %v, 期望只有来源中的REGION", got)
+	}
+	wantFields := map[string]string{"REGION": utils.DataField, "LOGO": utils.BinaryDataField}
+	if got := cache.KeyFields[utils.ConfigMapKind]["default"]["app-config"]; !reflect.DeepEqual(got, wantFields) {
+		t.Errorf("app-config的键来源 = %v, 期望 %v", got, wantFields)
+	}
+	if got := cache.BinaryData["default"]["app-config"]["LOGO"]; got != "hello" {
+		t.Errorf("LOGO = %q, 期望解码后的hello", got)
+	}
+
+	// 集群中只有键的Secret被来源中的完整对象替换
+	if got := cache.Secrets["default"]["db-secret"]; len(got) != 1 || got["PASSWORD"] != "shared" {
+		t.Errorf("db-secret = %v, 期望只有来源中的PASSWORD", got)
+	}
+	if cache.IsKeysOnly("default", "db-secret") {
+		t.Error("被替换的Secret不应再标记为只有键")
+	}
+	if got := cache.KeyField(utils.SecretKind, "default", "db-secret", "PASSWORD"); got != utils.StringDataField {
+		t.Errorf("PASSWORD的来源字段 = %q, 期望 %s", got, utils.StringDataField)
+	}
+	if got := cache.KeyField(utils.SecretKind, "default", "db-secret", "CLUSTER_ONLY"); got != "" {
+		t.Errorf("CLUSTER_ONLY的来源字段 = %q, 被替换的对象中的键不应保留", got)
+	}
+}
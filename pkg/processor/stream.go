@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 是否为流模式：从文件或标准输入读取，处理结果写入标准输出
+func (p *MainProcessor) streaming() bool {
+	return len(p.Options.Files) > 0
+}
+
+// 读取流模式的输入，"-"从stdin读取；按指定的顺序返回解析后的文件
+func (p *MainProcessor) LoadStream(stdin io.Reader) ([]*parser.File, error) {
+	var files []*parser.File
+	stdinRead := false
+
+	for _, name := range p.Options.Files {
+		var data []byte
+		var err error
+		filePath := name
+
+		if name == utils.StdinFile {
+			// 标准输入只能读取一次
+			if stdinRead {
+				return nil, fmt.Errorf("标准输入只能指定一次")
+			}
+			stdinRead = true
+			filePath = utils.StdinName
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %v", filePath, err)
+		}
+
+		files = append(files, p.Parser.ParseContent(filePath, data))
+	}
+
+	p.Report.TotalFiles = len(files)
+	p.Report.Sort()
+	return files, nil
+}
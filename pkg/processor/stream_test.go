@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+func TestLoadStream(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"config.yaml": testConfigMap})
+	configPath := filepath.Join(dir, "config.yaml")
+
+	tests := []struct {
+		name    string
+		files   []string
+		stdin   string
+		paths   []string
+		wantErr bool
+	}{
+		{"按指定顺序读取", []string{utils.StdinFile, configPath}, "kind: Service\n", []string{utils.StdinName, configPath}, false},
+		{"只读取文件", []string{configPath}, "", []string{configPath}, false},
+		{"标准输入只能指定一次", []string{utils.StdinFile, utils.StdinFile}, "kind: Service\n", nil, true},
+		{"文件不存在", []string{filepath.Join(dir, "missing.yaml")}, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.Files = tt.files
+			})
+			files, err := p.LoadStream(strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadStream() 错误 = %v, 期望错误: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.paths, ",") {
+				t.Errorf("文件 = %v, 期望 %v", paths, tt.paths)
+			}
+			if p.Report.TotalFiles != len(tt.paths) {
+				t.Errorf("TotalFiles = %d, 期望 %d", p.Report.TotalFiles, len(tt.paths))
+			}
+		})
+	}
+}
+
+// 流模式按输入顺序输出所有文件，文件之间缺少分隔符时补充，已有分隔符时不重复
+func TestStreamSeparators(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL`
+	service := "---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"
+
+	tests := []struct {
+		name  string
+		mode  string
+		check bool
+		want  string
+	}{
+		{
+			name: "输出处理后的YAML",
+			mode: utils.ModeSafe,
+			want: testConfigMap + "---\n" + deployment + `
+              valueFrom:
+                configMapKeyRef:
+                  name: app-config
+                  key: LOG_LEVEL
+` + service,
+		},
+		{
+			// 干运行只输出差异，不输出分隔符
+			name: "干运行",
+			mode: utils.ModeDryRun,
+		},
+		{
+			name:  "检查模式",
+			mode:  utils.ModeSafe,
+			check: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"app.yaml": deployment, "service.yaml": service})
+			p, output := newTestProcessor(t, func(options *utils.ProcessOptions) {
+				options.Mode = tt.mode
+				options.Check = tt.check
+				options.Files = []string{utils.StdinFile, filepath.Join(dir, "app.yaml"), filepath.Join(dir, "service.yaml")}
+			})
+			files, err := p.LoadStream(strings.NewReader(testConfigMap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.InitializeCache(context.Background(), files); err != nil {
+				t.Fatal(err)
+			}
+			p.ProcessFiles(files)
+
+			if tt.want != "" {
+				if output.String() != tt.want {
+					t.Errorf("输出 =\n%s\n期望\n%s", output.String(), tt.want)
+				}
+				return
+			}
+			if strings.Contains(output.String(), "\n---\n") || strings.Contains(output.String(), "kind: Service") {
+				t.Errorf("不应输出YAML流:\n%s", output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/secrets"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 生成的占位配置清单开头的说明
+const stubsHeader = "# 由 k8sconfig-processor --create-missing 生成，请将TODO替换为实际的值；Secret中的值为明文，提交前请加密\n"
+
+// 可以为新的配置对象命名的解析策略
+type stubNamer interface {
+	// 返回按该策略能被解析到的配置对象名称
+	stubName(request ResolveRequest, kind string) (string, bool)
+}
+
+func (exactResolver) stubName(request ResolveRequest, kind string) (string, bool) {
+	return ConfigNameForEnv(request.EnvName), true
+}
+
+func (workloadResolver) stubName(request ResolveRequest, kind string) (string, bool) {
+	if request.Workload == "" {
+		return "", false
+	}
+	if kind == utils.SecretKind {
+		return request.Workload + "-secret", true
+	}
+	return request.Workload + "-config", true
+}
+
+func (r prefixResolver) stubName(request ResolveRequest, kind string) (string, bool) {
+	for _, prefix := range r.prefixes {
+		if strings.HasPrefix(request.EnvName, prefix) {
+			return r.rules[prefix], true
+		}
+	}
+	return "", false
+}
+
+// 为无法解析的环境变量生成的占位配置对象，可以并发登记
+type stubSet struct {
+	mu      sync.Mutex
+	objects map[configKey]map[string]string
+	// 已有占位清单中的对象，可以继续追加键
+	generated map[configKey]bool
+	// 区分ConfigMap和Secret的敏感名称模式
+	patterns []string
+}
+
+// 新建占位配置对象集合
+func newStubSet(patterns []string) *stubSet {
+	return &stubSet{
+		objects:   make(map[configKey]map[string]string),
+		generated: make(map[configKey]bool),
+		patterns:  patterns,
+	}
+}
+
+// 登记已有占位清单中的对象，这些名称不视为已被占用
+func (s *stubSet) adopt(file *parser.File) {
+	if s == nil || file == nil {
+		return
+	}
+	for _, document := range file.Documents {
+		resource := &document.Resource
+		if document.Root() == nil || (resource.Kind != utils.ConfigMapKind && resource.Kind != utils.SecretKind) {
+			continue
+		}
+		s.generated[configKey{kind: resource.Kind, namespace: resource.Metadata.Namespace, name: resource.Metadata.Name}] = true
+	}
+}
+
+// 创建设置相同的空集合，用于试运行，试运行中的占位对象不会被写入
+func (s *stubSet) scratch() *stubSet {
+	if s == nil {
+		return nil
+	}
+	scratch := newStubSet(s.patterns)
+	for id := range s.generated {
+		scratch.generated[id] = true
+	}
+	return scratch
+}
+
+// 按解析策略链中第一个能命名且名称未被占用的策略登记占位键，返回指向它的解析结果；
+// 已存在的配置对象不会被占位对象覆盖。与scan使用同一分类规则区分ConfigMap和Secret
+func (s *stubSet) add(chain *ResolverChain, request ResolveRequest) Resolution {
+	kind := utils.ConfigMapKind
+	if secrets.IsSecret(request.EnvName, "", s.patterns) {
+		kind = utils.SecretKind
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resolution := Resolution{
+		Value:      utils.StubValue,
+		ConfigKind: kind,
+		Key:        request.EnvName,
+		Strategy:   utils.ResolverExact,
+	}
+	for _, resolver := range chain.Resolvers {
+		namer, ok := resolver.(stubNamer)
+		if !ok {
+			continue
+		}
+		if name, ok := namer.stubName(request, kind); ok && !s.taken(chain.ConfigCache, kind, request.Namespace, name) {
+			resolution.ConfigName = name
+			resolution.Strategy = resolver.Name()
+			break
+		}
+	}
+
+	// 没有可用的策略名称时使用环境变量对应的名称，被占用时追加序号
+	if resolution.ConfigName == "" {
+		base := ConfigNameForEnv(request.EnvName)
+		resolution.ConfigName = base
+		for i := 2; s.taken(chain.ConfigCache, kind, request.Namespace, resolution.ConfigName); i++ {
+			resolution.ConfigName = fmt.Sprintf("%s-%d", base, i)
+		}
+	}
+
+	id := configKey{kind: kind, namespace: request.Namespace, name: resolution.ConfigName}
+	if _, exists := s.objects[id]; !exists {
+		s.objects[id] = make(map[string]string)
+	}
+	s.objects[id][request.EnvName] = utils.StubValue
+	return resolution
+}
+
+// 判断名称是否已被占位清单以外的配置对象使用，调用方需持有锁
+func (s *stubSet) taken(cache *utils.ConfigCache, kind, namespace, name string) bool {
+	id := configKey{kind: kind, namespace: namespace, name: name}
+	if s.generated[id] || s.objects[id] != nil || cache == nil {
+		return false
+	}
+	_, exists := cacheObjects(cache, kind)[namespace][name]
+	return exists
+}
+
+// 已登记的占位对象中键的副本，对象不存在或未启用时返回nil
+func (s *stubSet) lookup(kind, namespace, name string) map[string]string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stub, exists := s.objects[configKey{kind: kind, namespace: namespace, name: name}]
+	if !exists {
+		return nil
+	}
+	data := make(map[string]string, len(stub))
+	for key, value := range stub {
+		data[key] = value
+	}
+	return data
+}
+
+// 已登记的占位对象的副本，未启用时返回nil
+func (s *stubSet) snapshot() map[configKey]map[string]string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	objects := make(map[configKey]map[string]string, len(s.objects))
+	for id, data := range s.objects {
+		objects[id] = make(map[string]string, len(data))
+		for key, value := range data {
+			objects[id][key] = value
+		}
+	}
+	return objects
+}
+
+// 占位配置清单的路径，流模式中只用于报告和输出
+func (p *MainProcessor) stubsPath() string {
+	if p.streaming() {
+		return utils.StubsFile
+	}
+	return filepath.Join(p.Options.InputDir, utils.StubsFile)
+}
+
+// 将输入中已生成的占位清单分离出来，由writeStubs在处理完成后合并写入
+func (p *MainProcessor) splitStubsFile(files []*parser.File) ([]*parser.File, *parser.File) {
+	if p.WorkloadProcessor.stubs == nil {
+		return files, nil
+	}
+
+	target := p.stubsPath()
+	var existing *parser.File
+	remaining := make([]*parser.File, 0, len(files))
+	for _, file := range files {
+		if samePath(file.Path, target) {
+			existing = file
+			continue
+		}
+		remaining = append(remaining, file)
+	}
+	return remaining, existing
+}
+
+// 生成占位清单文件：在已有的清单上追加新登记的键，已有的值保持不变；没有新的占位对象时返回已有的清单
+func (p *MainProcessor) stubsFile(existing *parser.File) (*parser.File, bool, error) {
+	stubs := p.WorkloadProcessor.stubs.snapshot()
+	if len(stubs) == 0 {
+		return existing, false, nil
+	}
+
+	objects := make(map[configKey]map[string]string)
+	if existing != nil {
+		p.collectGenerated(existing, objects)
+	}
+	for id, data := range stubs {
+		if _, exists := objects[id]; !exists {
+			objects[id] = make(map[string]string)
+		}
+		for key, value := range data {
+			if _, has := objects[id][key]; !has {
+				objects[id][key] = value
+			}
+		}
+	}
+
+	return p.generatedConfigFile(p.stubsPath(), stubsHeader, existing, objects)
+}
+
+// 处理完成后写入占位清单
+func (p *MainProcessor) writeStubs(existing *parser.File) error {
+	file, changed, err := p.stubsFile(existing)
+	if err != nil || file == nil {
+		return err
+	}
+	if existing == nil {
+		p.Report.TotalFiles++
+	}
+
+	p.processFiles([]*parser.File{file}, func(file *parser.File, worker *WorkloadProcessor) (bool, error) {
+		return changed, nil
+	})
+	return nil
+}
@@ -0,0 +1,211 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+func TestStubKind(t *testing.T) {
+	tests := []struct {
+		name      string
+		resolvers []string
+		patterns  []string
+		envName   string
+		kind      string
+		config    string
+	}{
+		{"普通变量", []string{utils.ResolverExact}, nil, "LOG_LEVEL", utils.ConfigMapKind, "log-level"},
+		{"密码", []string{utils.ResolverExact}, nil, "DB_PASSWORD", utils.SecretKind, "db-password"},
+		{"令牌", []string{utils.ResolverExact}, nil, "API_TOKEN", utils.SecretKind, "api-token"},
+		// 短模式按分段匹配
+		{"包含KEY的普通名称", []string{utils.ResolverExact}, nil, "MONKEY", utils.ConfigMapKind, "monkey"},
+		{"workload中的Secret", []string{utils.ResolverWorkload, utils.ResolverExact}, nil, "DB_PASSWORD", utils.SecretKind, "web-secret"},
+		{"workload中的ConfigMap", []string{utils.ResolverWorkload, utils.ResolverExact}, nil, "PORT", utils.ConfigMapKind, "web-config"},
+		{"自定义模式", []string{utils.ResolverExact}, []string{"LICENSE"}, "APP_LICENSE", utils.SecretKind, "app-license"},
+		{"自定义模式替换默认模式", []string{utils.ResolverExact}, []string{"LICENSE"}, "DB_PASSWORD", utils.ConfigMapKind, "db-password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := NewResolverChain(tt.resolvers, nil, utils.NewConfigCache())
+			if err != nil {
+				t.Fatal(err)
+			}
+			stubs := newStubSet(tt.patterns)
+			resolution := stubs.add(chain, ResolveRequest{EnvName: tt.envName, Namespace: utils.DefaultNamespace, Workload: "web"})
+			if resolution.ConfigKind != tt.kind || resolution.ConfigName != tt.config || resolution.Value != utils.StubValue {
+				t.Errorf("add(%s) = %+v, 期望 %s/%s", tt.envName, resolution, tt.kind, tt.config)
+			}
+			if data := stubs.lookup(tt.kind, utils.DefaultNamespace, tt.config); data[tt.envName] != utils.StubValue {
+				t.Errorf("占位对象 %s/%s = %v, 缺少 %s", tt.kind, tt.config, data, tt.envName)
+			}
+		})
+	}
+}
+
+// 重复执行不改变占位清单；新的变量追加到清单中，已填写的值保持不变
+func TestStubsIdempotent(t *testing.T) {
+	inputDir := t.TempDir()
+	workload := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: LOG_LEVEL
+            - name: DB_PASSWORD
+`
+	writeFiles(t, inputDir, map[string]string{"app.yaml": workload})
+	stubsPath := filepath.Join(inputDir, utils.StubsFile)
+
+	run := func() string {
+		t.Helper()
+		p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+			options.InputDir = inputDir
+			options.Mode = utils.ModeOverwrite
+			options.Force = true
+			options.CreateMissing = true
+		})
+		if err := p.Execute(); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		data, err := os.ReadFile(stubsPath)
+		if err != nil {
+			t.Fatalf("读取占位清单失败: %v", err)
+		}
+		return string(data)
+	}
+
+	first := run()
+	for _, want := range []string{"kind: ConfigMap\nmetadata:\n  name: log-level", "kind: Secret\nmetadata:\n  name: db-password"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("占位清单应包含 %q:\n%s", want, first)
+		}
+	}
+
+	if second := run(); second != first {
+		t.Errorf("重复执行不应改变占位清单:\n%s\n期望\n%s", second, first)
+	}
+
+	// 填写占位值并添加新的变量
+	filled := strings.Replace(first, "LOG_LEVEL: "+utils.StubValue, "LOG_LEVEL: debug", 1)
+	if filled == first {
+		t.Fatalf("占位清单中缺少LOG_LEVEL:\n%s", first)
+	}
+	writeFiles(t, inputDir, map[string]string{utils.StubsFile: filled})
+	app, err := os.ReadFile(filepath.Join(inputDir, "app.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, inputDir, map[string]string{"app.yaml": string(app) + "            - name: API_TOKEN\n"})
+
+	// 新的对象按名称排序插入，已有对象中填写的值保持不变
+	third := run()
+	if !strings.Contains(third, "LOG_LEVEL: debug") || !strings.Contains(third, "DB_PASSWORD: "+utils.StubValue) {
+		t.Errorf("已有的值应保持不变:\n%s", third)
+	}
+	for _, name := range []string{"log-level", "db-password", "api-token"} {
+		if count := strings.Count(third, "name: "+name+"\n"); count != 1 {
+			t.Errorf("%s 出现 %d 次, 期望 1 次:\n%s", name, count, third)
+		}
+	}
+	if !strings.Contains(third, "kind: Secret\nmetadata:\n  name: api-token") {
+		t.Errorf("api-token应生成为Secret:\n%s", third)
+	}
+	if fourth := run(); fourth != third {
+		t.Errorf("重复执行不应改变占位清单:\n%s\n期望\n%s", fourth, third)
+	}
+}
+
+// 占位对象不能覆盖已存在的配置对象；已有占位清单中的对象可以继续追加键
+func TestStubsSkipExistingNames(t *testing.T) {
+	inputDir := t.TempDir()
+	config := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  LOG_LEVEL: info
+`
+	workloads := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          env:
+            - name: PORT
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+        - name: api
+          env:
+            - name: PORT
+`
+	writeFiles(t, inputDir, map[string]string{"config.yaml": config, "app.yaml": workloads})
+
+	run := func() (*MainProcessor, string) {
+		t.Helper()
+		p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+			options.InputDir = inputDir
+			options.Mode = utils.ModeOverwrite
+			options.Force = true
+			options.CreateMissing = true
+			options.Resolvers = []string{utils.ResolverWorkload, utils.ResolverExact}
+		})
+		if err := p.Execute(); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		for _, finding := range p.Report.Findings {
+			if finding.Code == utils.CodeMissingReference {
+				t.Errorf("占位引用不应报告为缺失: %s", finding.Message)
+			}
+		}
+		data, err := os.ReadFile(filepath.Join(inputDir, utils.StubsFile))
+		if err != nil {
+			t.Fatalf("读取占位清单失败: %v", err)
+		}
+		return p, string(data)
+	}
+
+	_, first := run()
+	if strings.Contains(first, "name: web-config") {
+		t.Errorf("不应重新定义已存在的web-config:\n%s", first)
+	}
+	for _, want := range []string{"name: port\n", "name: api-config\n"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("占位清单应包含 %q:\n%s", want, first)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(inputDir, "config.yaml")); string(data) != config {
+		t.Errorf("已存在的配置对象不应被修改:\n%s", data)
+	}
+
+	// 新的变量追加到已生成的api-config中，引用检查合并本次的占位键
+	app, err := os.ReadFile(filepath.Join(inputDir, "app.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, inputDir, map[string]string{"app.yaml": string(app) + "            - name: HOST\n"})
+	_, second := run()
+	if strings.Count(second, "name: api-config\n") != 1 || !strings.Contains(second, "HOST: "+utils.StubValue) {
+		t.Errorf("HOST应追加到api-config:\n%s", second)
+	}
+}
@@ -0,0 +1,218 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置对象的标识
+type configKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// 将所有ConfigMap和Secret清单重命名为<名称>-<内容哈希>并设置immutable: true，
+// 然后将所有工作负载中的引用改写为新名称；内容不变时重复执行不会产生变更
+func (p *MainProcessor) Version() error {
+	files, err := p.loadInput()
+	if err != nil {
+		return err
+	}
+	// 缺少文件时无法保证所有引用都被改写
+	if len(p.loadErrors) > 0 {
+		return p.loadErrors[0]
+	}
+	// kustomization中的命名空间决定引用指向哪个对象
+	if _, err := p.loadKustomizations(files); err != nil {
+		return err
+	}
+
+	// 第一遍：重命名配置对象，记录原名称和旧的带哈希名称到新名称的映射
+	renames := make(map[configKey]string)
+	versioned := make(map[*parser.File]bool)
+	for _, file := range files {
+		for _, document := range file.Documents {
+			if document.Root() == nil {
+				continue
+			}
+			kind := document.Resource.Kind
+			if kind != utils.ConfigMapKind && kind != utils.SecretKind {
+				continue
+			}
+
+			modified, err := p.versionConfig(document, renames)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file.Path, err)
+			}
+			if modified {
+				versioned[file] = true
+			}
+		}
+	}
+
+	// 第二遍：并发改写引用并写入输出
+	p.processFiles(files, func(file *parser.File, worker *WorkloadProcessor) (bool, error) {
+		modified := versioned[file]
+		for _, document := range file.Documents {
+			if document.Root() != nil && rewriteReferences(worker, document, renames) {
+				modified = true
+			}
+		}
+		return modified, nil
+	})
+
+	p.Report.Sort()
+	return p.PrintReport()
+}
+
+// 为配置对象设置带内容哈希的名称、原始名称注解和immutable字段
+func (p *MainProcessor) versionConfig(document *parser.Document, renames map[configKey]string) (bool, error) {
+	resource := &document.Resource
+	root := document.Root()
+	metadata := parser.MappingValue(root, "metadata")
+	if metadata == nil || metadata.Kind != yaml.MappingNode {
+		return false, fmt.Errorf("%s 缺少metadata字段", resource.Kind)
+	}
+
+	hash, err := contentHash(resource)
+	if err != nil {
+		return false, err
+	}
+
+	// 已经带哈希的对象从注解中取得原始名称
+	baseName := resource.Metadata.Name
+	annotations := parser.MappingValue(metadata, "annotations")
+	if annotated, _ := parser.MappingString(annotations, utils.BaseNameAnnotation); annotated != "" {
+		baseName = annotated
+	}
+	newName := baseName + "-" + hash
+
+	modified := false
+	if resource.Metadata.Name != newName {
+		parser.SetMappingValue(metadata, "name", parser.NewScalar(newName))
+		modified = true
+		p.Report.Add(utils.Finding{
+			Severity:  utils.SeverityInfo,
+			Code:      utils.CodeConfigVersioned,
+			File:      document.Path,
+			Line:      document.Line(metadata),
+			Namespace: resource.Metadata.Namespace,
+			Message:   fmt.Sprintf("%s %s/%s 重命名为 %s", resource.Kind, resource.Metadata.Namespace, resource.Metadata.Name, newName),
+		})
+	}
+
+	if annotated, _ := parser.MappingString(annotations, utils.BaseNameAnnotation); annotated != baseName {
+		if annotations == nil || annotations.Kind != yaml.MappingNode {
+			annotations = parser.NewMapping()
+			parser.SetMappingValue(metadata, "annotations", annotations)
+		}
+		parser.SetMappingValue(annotations, utils.BaseNameAnnotation, parser.NewScalar(baseName))
+		modified = true
+	}
+
+	if immutable, _ := parser.MappingString(root, "immutable"); immutable != "true" {
+		parser.SetMappingValue(root, "immutable", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		modified = true
+	}
+
+	// 引用可能使用原始名称，也可能使用上一次生成的名称
+	for _, name := range []string{baseName, resource.Metadata.Name} {
+		renames[configKey{kind: resource.Kind, namespace: resource.Metadata.Namespace, name: name}] = newName
+	}
+
+	return modified, nil
+}
+
+// 将工作负载中的配置引用改写为新名称，返回是否有改写
+func rewriteReferences(worker *WorkloadProcessor, document *parser.Document, renames map[configKey]string) bool {
+	namespace := document.Resource.Metadata.Namespace
+	modified := false
+
+	for _, spec := range worker.PodSpecs(document) {
+		forEachConfigReference(spec, func(kind string, node *yaml.Node, nameField string) {
+			name, _ := parser.MappingString(node, nameField)
+			newName, exists := renames[configKey{kind: kind, namespace: namespace, name: name}]
+			if !exists || newName == name {
+				return
+			}
+
+			parser.SetMappingValue(node, nameField, parser.NewScalar(newName))
+			modified = true
+			worker.Report.Add(utils.Finding{
+				Severity:  utils.SeverityInfo,
+				Code:      utils.CodeReferenceRewrite,
+				File:      document.Path,
+				Line:      document.Line(node),
+				Namespace: namespace,
+				Workload:  document.Resource.Metadata.Name,
+				Message:   fmt.Sprintf("%s 引用 %s 改写为 %s (资源: %s/%s)", kind, name, newName, namespace, document.Resource.Metadata.Name),
+			})
+		})
+	}
+
+	if modified {
+		worker.Report.SuccessfulUpdates++
+	}
+	return modified
+}
+
+// 计算配置对象内容的哈希，与kustomize生成器的名称后缀哈希一致：参与哈希的字段编码为键已排序的JSON，
+// 取SHA-256的前10个十六进制字符，并替换容易组成单词的字符
+func contentHash(resource *utils.KubeResource) (string, error) {
+	data, err := json.Marshal(hashedContent(resource))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	encoded := []byte(hex.EncodeToString(sum[:])[:10])
+	for i, c := range encoded {
+		switch c {
+		case '0':
+			encoded[i] = 'g'
+		case '1':
+			encoded[i] = 'h'
+		case '3':
+			encoded[i] = 'k'
+		case 'a':
+			encoded[i] = 'm'
+		case 'e':
+			encoded[i] = 't'
+		}
+	}
+	return string(encoded), nil
+}
+
+// 参与哈希的字段，与kustomize的encodeConfigMap/encodeSecret相同：缺少data和type时编码为空字符串，
+// binaryData和stringData只在存在时加入，Secret的data保持base64编码。
+// kustomize按"metadata/name"查找名称时找不到该字段，名称总是编码为空字符串，因此哈希与名称无关
+func hashedContent(resource *utils.KubeResource) map[string]interface{} {
+	orEmpty := func(values map[string]string) interface{} {
+		if values == nil {
+			return ""
+		}
+		return values
+	}
+
+	content := map[string]interface{}{
+		"kind": resource.Kind,
+		"name": "",
+		"data": orEmpty(resource.Data),
+	}
+	if resource.Kind == utils.SecretKind {
+		content["type"] = resource.Type
+		if resource.StringData != nil {
+			content["stringData"] = resource.StringData
+		}
+	} else if resource.BinaryData != nil {
+		content["binaryData"] = resource.BinaryData
+	}
+	return content
+}
@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/parser"
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 期望值由kustomize api/hasher（v0.18.0至v0.21.1的实现相同）对同样的清单计算得到
+func TestContentHashMatchesKustomize(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"ConfigMap", "kind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  LOG_LEVEL: info\n  PORT: \"8080\"\n", "bt5mh2dcg8"},
+		{"名称不参与哈希", "kind: ConfigMap\nmetadata:\n  name: other-config\ndata:\n  LOG_LEVEL: info\n  PORT: \"8080\"\n", "bt5mh2dcg8"},
+		{"binaryData", "kind: ConfigMap\nmetadata:\n  name: app-config\ndata:\n  LOG_LEVEL: info\nbinaryData:\n  cert.der: AQID\n", "778c2kmb8t"},
+		{"没有data", "kind: ConfigMap\nmetadata:\n  name: empty\n", "6ct58987ht"},
+		{"特殊字符和多行值", "kind: ConfigMap\nmetadata:\n  name: html\ndata:\n  URL: \"https://example.com/?a=1&b=<2>\"\n  MULTI: |\n    line1\n    line2\n", "ktmh76965d"},
+		{"Secret", "kind: Secret\nmetadata:\n  name: db-secret\ntype: Opaque\ndata:\n  PASSWORD: czNjcmV0\n", "k756ct962b"},
+		{"Secret没有type", "kind: Secret\nmetadata:\n  name: db-secret\ndata:\n  PASSWORD: czNjcmV0\n", "6544hht42b"},
+		{"Secret的stringData", "kind: Secret\nmetadata:\n  name: db-secret\ntype: Opaque\nstringData:\n  PASSWORD: s3cret\n", "5972fbd47g"},
+	}
+
+	yamlParser := parser.NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := yamlParser.ParseContent("config.yaml", []byte("apiVersion: v1\n"+tt.manifest))
+			got, err := contentHash(&file.Documents[0].Resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("contentHash = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+const versionConfig = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  LOG_LEVEL: info
+`
+
+const versionWorkload = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+        - name: web
+          envFrom:
+            - configMapRef:
+                name: app-config
+      volumes:
+        - name: config
+          configMap:
+            name: app-config
+`
+
+// 在inputDir上执行Version，返回输出目录中的文件内容和处理器
+func runVersion(t *testing.T, inputDir string) (map[string]string, *MainProcessor) {
+	t.Helper()
+	p, _ := newTestProcessor(t, func(options *utils.ProcessOptions) {
+		options.InputDir = inputDir
+	})
+	if err := p.Version(); err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	return readOutputDir(t, p.Options.OutputDir), p
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVersionIdempotentAndRehash(t *testing.T) {
+	inputDir := t.TempDir()
+	writeFiles(t, inputDir, map[string]string{"config.yaml": versionConfig, "app.yaml": versionWorkload})
+
+	first, _ := runVersion(t, inputDir)
+	hashed := hashedName(t, first["config.yaml"])
+	if !strings.HasPrefix(hashed, "app-config-") {
+		t.Fatalf("配置对象应改为带哈希的名称:\n%s", first["config.yaml"])
+	}
+	if !strings.Contains(first["config.yaml"], utils.BaseNameAnnotation+": app-config") ||
+		!strings.Contains(first["config.yaml"], "immutable: true") {
+		t.Errorf("缺少原始名称注解或immutable:\n%s", first["config.yaml"])
+	}
+	if strings.Count(first["app.yaml"], hashed) != 2 {
+		t.Errorf("env和卷中的引用都应改写:\n%s", first["app.yaml"])
+	}
+
+	// 在输出上再次执行不产生任何变更
+	secondInput := t.TempDir()
+	writeFiles(t, secondInput, first)
+	second, p := runVersion(t, secondInput)
+	if len(second) != 0 || p.Report.ProcessedFiles != 0 {
+		t.Errorf("再次执行不应修改文件, 实际修改了 %d 个: %v", len(second), second)
+	}
+
+	// 修改已带哈希的对象的内容：按原始名称生成新名称，引用从旧的带哈希名称改写为新名称
+	thirdInput := t.TempDir()
+	writeFiles(t, thirdInput, map[string]string{
+		"config.yaml": strings.Replace(first["config.yaml"], "LOG_LEVEL: info", "LOG_LEVEL: debug", 1),
+		"app.yaml":    first["app.yaml"],
+	})
+	third, _ := runVersion(t, thirdInput)
+	newName := hashedName(t, third["config.yaml"])
+	if newName == hashed || !strings.HasPrefix(newName, "app-config-") || strings.Count(newName, "-") != 2 {
+		t.Errorf("新名称 %s 应基于原始名称生成, 旧名称 %s", newName, hashed)
+	}
+	if strings.Contains(third["app.yaml"], hashed) || strings.Count(third["app.yaml"], newName) != 2 {
+		t.Errorf("引用应从 %s 改写为 %s:\n%s", hashed, newName, third["app.yaml"])
+	}
+	if strings.Count(third["config.yaml"], utils.BaseNameAnnotation) != 1 {
+		t.Errorf("原始名称注解不应重复:\n%s", third["config.yaml"])
+	}
+}
+
+// 取出清单中ConfigMap的名称
+func hashedName(t *testing.T, manifest string) string {
+	t.Helper()
+	file := parser.NewYAMLParser(utils.NewConfigCache(), utils.NewProcessReport()).ParseContent("config.yaml", []byte(manifest))
+	if len(file.Documents) == 0 || file.Documents[0].Root() == nil {
+		t.Fatalf("无法解析清单:\n%s", manifest)
+	}
+	return file.Documents[0].Resource.Metadata.Name
+}
@@ -2,8 +2,11 @@ package processor
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/k8sconfig-processor/pkg/parser"
 	"github.com/k8sconfig-processor/pkg/utils"
+	"gopkg.in/yaml.v3"
 )
 
 // 工作负载处理器
@@ -12,176 +15,528 @@ type WorkloadProcessor struct {
 	ConfigCache *utils.ConfigCache
 	// 处理报告
 	Report *utils.ProcessReport
+	// 名称解析策略链
+	Resolver *ResolverChain
+	// 工作负载注册表
+	Registry *WorkloadRegistry
+	// 环境变量引用风格: keyRef, envFrom, auto
+	EnvStyle string
+	// 是否在pod模板上写入引用的配置对象的哈希注解
+	Checksums bool
+
+	// 为无法解析的环境变量登记占位配置对象，为空时只报告警告
+	stubs *stubSet
 }
 
 // 创建新的工作负载处理器
-func NewWorkloadProcessor(configCache *utils.ConfigCache, report *utils.ProcessReport) *WorkloadProcessor {
+func NewWorkloadProcessor(configCache *utils.ConfigCache, report *utils.ProcessReport, resolver *ResolverChain) *WorkloadProcessor {
 	return &WorkloadProcessor{
 		ConfigCache: configCache,
 		Report:      report,
+		Resolver:    resolver,
+		Registry:    NewWorkloadRegistry(),
+		EnvStyle:    utils.EnvStyleKeyRef,
+	}
+}
+
+// 创建使用独立报告的副本，共享缓存、解析策略和注册表，用于并发处理
+func (p *WorkloadProcessor) WithReport(report *utils.ProcessReport) *WorkloadProcessor {
+	worker := *p
+	worker.Report = report
+	return &worker
+}
+
+// 需要处理的容器列表字段
+var ContainerLists = []string{
+	utils.ContainersField,
+	utils.InitContainersField,
+	utils.EphemeralContainersField,
+}
+
+// 容器在清单中的位置，用于报告
+type ContainerLocation struct {
+	// 命名空间
+	Namespace string
+	// 工作负载名称
+	Workload string
+	// 容器列表字段
+	List string
+	// 容器名称
+	Container string
+	// 是否为原生sidecar
+	Sidecar bool
+
+	// 所在文档，用于计算报告中的文件和行号
+	document *parser.Document
+}
+
+// 格式化为报告中使用的位置描述
+func (l ContainerLocation) String() string {
+	list := l.List
+	if l.Sidecar {
+		list += "(sidecar)"
+	}
+	return fmt.Sprintf("资源: %s/%s, %s: %s", l.Namespace, l.Workload, list, l.Container)
+}
+
+// 创建位于容器中指定节点的报告条目
+func (l ContainerLocation) finding(severity, code string, node *yaml.Node, envName string) utils.Finding {
+	finding := utils.Finding{
+		Severity:  severity,
+		Code:      code,
+		Namespace: l.Namespace,
+		Workload:  l.Workload,
+		Container: l.Container,
+		EnvVar:    envName,
+	}
+	if l.document != nil {
+		finding.File = l.document.Path
+		finding.Line = l.document.Line(node)
 	}
+	return finding
 }
 
-// 判断是否为工作负载资源
-func IsWorkloadResource(kind string) bool {
-	return kind == utils.DeploymentKind ||
-		kind == utils.StatefulSetKind ||
-		kind == utils.DaemonSetKind
+// 判断是否为内置的工作负载资源
+func IsWorkloadResource(apiVersion, kind string) bool {
+	builtin, exists := builtinPodSpecPaths[kind]
+	return exists && matchAPIVersion(builtin.apiVersion, apiVersion)
 }
 
 // 处理工作负载资源
-func (p *WorkloadProcessor) ProcessWorkload(resource *utils.KubeResource) (bool, error) {
+func (p *WorkloadProcessor) ProcessWorkload(document *parser.Document) (bool, error) {
+	resource := &document.Resource
+
 	// 验证资源类型
-	if !IsWorkloadResource(resource.Kind) {
-		return false, nil
+	paths := p.Registry.PodSpecPaths(resource.APIVersion, resource.Kind)
+	if len(paths) == 0 {
+		return p.processDiscoveredWorkload(document)
 	}
 
 	modified := false
 	namespace := resource.Metadata.Namespace
 	resourceName := resource.Metadata.Name
 
-	// 获取pod模板
-	template, exists := resource.Spec["template"]
-	if !exists {
-		p.Report.Warnings = append(p.Report.Warnings,
-			fmt.Sprintf("资源 %s/%s 没有template字段", namespace, resourceName))
-		return false, nil
+	// 修改之前检查所有pod规格的格式，格式无效的资源不留下部分修改和报告条目
+	specs := make([]*yaml.Node, len(paths))
+	for i, path := range paths {
+		spec, err := findPodSpec(document.Root(), path)
+		if err != nil {
+			return false, fmt.Errorf("资源 %s/%s 的%v", namespace, resourceName, err)
+		}
+		if err := validatePodSpec(document, spec); err != nil {
+			return false, err
+		}
+		specs[i] = spec
 	}
 
-	// 获取pod规格
-	templateMap, ok := template.(map[string]interface{})
-	if !ok {
-		return false, fmt.Errorf("资源 %s/%s 的template字段格式无效", namespace, resourceName)
-	}
+	// 按注册的路径逐个处理pod规格
+	for i, path := range paths {
+		spec := specs[i]
+		if spec == nil {
+			p.Report.Add(utils.Finding{
+				Severity:  utils.SeverityWarning,
+				Code:      utils.CodeMissingPodSpec,
+				File:      document.Path,
+				Line:      document.Line(document.Root()),
+				Namespace: namespace,
+				Workload:  resourceName,
+				Message:   fmt.Sprintf("资源 %s/%s 没有%s字段", namespace, resourceName, strings.Join(path, ".")),
+			})
+			continue
+		}
 
-	spec, exists := templateMap["spec"]
-	if !exists {
-		p.Report.Warnings = append(p.Report.Warnings,
-			fmt.Sprintf("资源 %s/%s 没有spec字段", namespace, resourceName))
-		return false, nil
+		specModified, err := p.processPodSpec(document, spec)
+		if err != nil {
+			return false, err
+		}
+
+		if specModified {
+			modified = true
+		}
 	}
 
-	// 获取容器列表
-	specMap, ok := spec.(map[string]interface{})
-	if !ok {
-		return false, fmt.Errorf("资源 %s/%s 的spec字段格式无效", namespace, resourceName)
+	// 引用的配置对象的哈希注解
+	if p.Checksums && p.annotateChecksums(document, paths) {
+		modified = true
 	}
 
-	containers, exists := specMap["containers"]
-	if !exists {
-		p.Report.Warnings = append(p.Report.Warnings,
-			fmt.Sprintf("资源 %s/%s 没有containers字段", namespace, resourceName))
-		return false, nil
+	// 节点树已原地修改，只需更新统计
+	if modified {
+		p.Report.SuccessfulUpdates++
 	}
 
-	// 遍历容器
-	containersList, ok := containers.([]interface{})
-	if !ok {
-		return false, fmt.Errorf("资源 %s/%s 的containers字段格式无效", namespace, resourceName)
+	return modified, nil
+}
+
+// 在未注册的资源中自动查找并处理pod规格
+func (p *WorkloadProcessor) processDiscoveredWorkload(document *parser.Document) (bool, error) {
+	resource := &document.Resource
+
+	// 仅在启用自动发现时处理，配置资源本身不含容器
+	if !p.Registry.Discover || resource.Kind == utils.ConfigMapKind || resource.Kind == utils.SecretKind {
+		return false, nil
 	}
 
-	for i, container := range containersList {
-		containerMap, ok := container.(map[string]interface{})
-		if !ok {
-			continue
+	specs := DiscoverPodSpecs(document.Root())
+	for _, spec := range specs {
+		if err := validatePodSpec(document, spec); err != nil {
+			return false, err
 		}
+	}
 
-		// 处理环境变量
-		envModified, err := p.processContainerEnv(containerMap, namespace, resourceName)
+	modified := false
+	for _, spec := range specs {
+		specModified, err := p.processPodSpec(document, spec)
 		if err != nil {
 			return false, err
 		}
 
-		if envModified {
+		if specModified {
 			modified = true
-			// 更新容器
-			containersList[i] = containerMap
 		}
 	}
 
-	// 如果有修改，更新容器列表
 	if modified {
-		specMap["containers"] = containersList
-		templateMap["spec"] = specMap
-		resource.Spec["template"] = templateMap
 		p.Report.SuccessfulUpdates++
 	}
 
 	return modified, nil
 }
 
-// 处理容器环境变量
-func (p *WorkloadProcessor) processContainerEnv(container map[string]interface{}, namespace, resourceName string) (bool, error) {
+// 获取资源中所有的pod规格节点，不产生报告
+func (p *WorkloadProcessor) PodSpecs(document *parser.Document) []*yaml.Node {
+	resource := &document.Resource
+
+	paths := p.Registry.PodSpecPaths(resource.APIVersion, resource.Kind)
+	if len(paths) == 0 {
+		if !p.Registry.Discover || resource.Kind == utils.ConfigMapKind || resource.Kind == utils.SecretKind {
+			return nil
+		}
+		return DiscoverPodSpecs(document.Root())
+	}
+
+	var specs []*yaml.Node
+	for _, path := range paths {
+		if spec, err := findPodSpec(document.Root(), path); err == nil && spec != nil {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// 遍历pod规格中所有容器列表中的容器
+func forEachContainer(spec *yaml.Node, fn func(listName string, container *yaml.Node)) {
+	for _, listName := range ContainerLists {
+		containers := parser.MappingValue(spec, listName)
+		if containers == nil || containers.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, container := range containers.Content {
+			if container.Kind == yaml.MappingNode {
+				fn(listName, container)
+			}
+		}
+	}
+}
+
+// 按路径查找pod规格，路径不存在时返回nil
+func findPodSpec(root *yaml.Node, path []string) (*yaml.Node, error) {
+	node := root
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s字段格式无效", strings.Join(path[:i], "."))
+		}
+		node = parser.MappingValue(node, key)
+		if node == nil {
+			return nil, nil
+		}
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s字段格式无效", strings.Join(path, "."))
+	}
+
+	return node, nil
+}
+
+// 处理pod规格中的所有容器
+func (p *WorkloadProcessor) processPodSpec(document *parser.Document, spec *yaml.Node) (bool, error) {
+	namespace := document.Resource.Metadata.Namespace
+	resourceName := document.Resource.Metadata.Name
 	modified := false
 
+	if parser.MappingValue(spec, utils.ContainersField) == nil {
+		p.Report.Add(utils.Finding{
+			Severity:  utils.SeverityWarning,
+			Code:      utils.CodeMissingContainers,
+			File:      document.Path,
+			Line:      document.Line(spec),
+			Namespace: namespace,
+			Workload:  resourceName,
+			Message:   fmt.Sprintf("资源 %s/%s 没有containers字段", namespace, resourceName),
+		})
+	}
+
+	// 遍历所有容器列表
+	for _, listName := range ContainerLists {
+		containers := parser.MappingValue(spec, listName)
+		if containers == nil {
+			continue
+		}
+
+		if containers.Kind != yaml.SequenceNode {
+			return false, fmt.Errorf("资源 %s/%s 的%s字段格式无效", namespace, resourceName, listName)
+		}
+
+		for _, container := range containers.Content {
+			if container.Kind != yaml.MappingNode {
+				continue
+			}
+
+			// 处理环境变量
+			envModified, err := p.processContainerEnv(container, newContainerLocation(document, listName, container))
+			if err != nil {
+				return false, err
+			}
+
+			if envModified {
+				modified = true
+			}
+		}
+	}
+
+	return modified, nil
+}
+
+// 创建容器在报告中的位置
+func newContainerLocation(document *parser.Document, listName string, container *yaml.Node) ContainerLocation {
+	containerName, _ := parser.MappingString(container, "name")
+	location := ContainerLocation{
+		Namespace: document.Resource.Metadata.Namespace,
+		Workload:  document.Resource.Metadata.Name,
+		List:      listName,
+		Container: containerName,
+		document:  document,
+	}
+
+	// 以restartPolicy: Always运行的初始化容器为原生sidecar
+	if restartPolicy, _ := parser.MappingString(container, "restartPolicy"); listName == utils.InitContainersField && restartPolicy == "Always" {
+		location.Sidecar = true
+	}
+	return location
+}
+
+// 检查pod规格中的容器列表和env字段是否为列表（别名等其他节点无法安全地原地修改），pod规格为空时不检查
+func validatePodSpec(document *parser.Document, spec *yaml.Node) error {
+	if spec == nil {
+		return nil
+	}
+	for _, listName := range ContainerLists {
+		containers := parser.MappingValue(spec, listName)
+		if containers == nil {
+			continue
+		}
+		if containers.Kind != yaml.SequenceNode {
+			return fmt.Errorf("资源 %s/%s 的%s字段格式无效", document.Resource.Metadata.Namespace, document.Resource.Metadata.Name, listName)
+		}
+		for _, container := range containers.Content {
+			if container.Kind != yaml.MappingNode {
+				continue
+			}
+			if env := parser.MappingValue(container, "env"); env != nil && env.Kind != yaml.SequenceNode {
+				return fmt.Errorf("%s 的env字段格式无效", newContainerLocation(document, listName, container))
+			}
+		}
+	}
+	return nil
+}
+
+// 处理容器环境变量
+func (p *WorkloadProcessor) processContainerEnv(container *yaml.Node, location ContainerLocation) (bool, error) {
+	namespace, resourceName := location.Namespace, location.Workload
+
 	// 获取环境变量列表
-	env, exists := container["env"]
-	if !exists {
+	env := parser.MappingValue(container, "env")
+	if env == nil {
 		// 没有环境变量，不需要处理
 		return false, nil
 	}
 
-	envList, ok := env.([]interface{})
-	if !ok {
-		return false, fmt.Errorf("资源 %s/%s 的env字段格式无效", namespace, resourceName)
+	if env.Kind != yaml.SequenceNode {
+		return false, fmt.Errorf("%s 的env字段格式无效", location)
 	}
 
+	sources := envFromSources(container)
+
+	// 按配置对象分组的待填充环境变量，保持首次出现的顺序
+	var groupOrder []string
+	groups := make(map[string][]*pendingEnv)
+	var satisfied []*pendingEnv
+
 	// 遍历环境变量
-	for i, envVar := range envList {
-		envVarMap, ok := envVar.(map[string]interface{})
-		if !ok {
+	for _, envVar := range env.Content {
+		if envVar.Kind != yaml.MappingNode {
 			continue
 		}
 
 		// 检查是否满足处理条件
-		name, hasName := envVarMap["name"]
-		_, hasValue := envVarMap["value"]
-		_, hasValueFrom := envVarMap["valueFrom"]
+		envName, hasName := parser.MappingString(envVar, "name")
+		hasValue := parser.MappingValue(envVar, "value") != nil
+		hasValueFrom := parser.MappingValue(envVar, "valueFrom") != nil
 
 		// 如果有name字段，但没有value和valueFrom，则需要处理
-		if hasName && !hasValue && !hasValueFrom {
-			envName, ok := name.(string)
-			if !ok {
-				continue
+		if !hasName || hasValue || hasValueFrom {
+			continue
+		}
+
+		// 已由容器现有的envFrom提供
+		if resolution, found := resolveFromEnvFrom(envName, namespace, sources, p.ConfigCache); found {
+			satisfied = append(satisfied, &pendingEnv{node: envVar, name: envName, resolution: resolution})
+			continue
+		}
+
+		// 按解析策略链查找配置
+		request := ResolveRequest{
+			EnvName:   envName,
+			Namespace: namespace,
+			Workload:  resourceName,
+		}
+		resolution, candidates, found := p.Resolver.Resolve(request)
+		if !found && p.stubs != nil {
+			// 引用新生成的占位配置对象
+			resolution, found = p.stubs.add(p.Resolver, request), true
+			finding := location.finding(utils.SeverityWarning, utils.CodeStubCreated, envVar, envName)
+			finding.Message = fmt.Sprintf("未找到环境变量 %s 的配置，已引用占位 %s %s (%s)", envName, resolution.ConfigKind, resolution.ConfigName, location)
+			finding.Suggestion = fmt.Sprintf("在 %s 中将 %s 的值替换为实际的值", utils.StubsFile, envName)
+			p.Report.Add(finding)
+		}
+		if !found {
+			// 未找到配置，添加警告
+			finding := location.finding(utils.SeverityWarning, utils.CodeEnvUnresolved, envVar, envName)
+			finding.Message = fmt.Sprintf("未找到环境变量 %s 的配置 (%s)", envName, location)
+			finding.Suggestion = fmt.Sprintf("在命名空间 %s 中创建包含键 %s 的ConfigMap或Secret，或为其设置value", namespace, envName)
+			p.Report.Add(finding)
+			continue
+		}
+
+		// 多个配置对象提供同一个键时报告歧义
+		if len(candidates) > 1 {
+			var candidateNames []string
+			for _, candidate := range candidates {
+				candidateNames = append(candidateNames, candidate.ConfigKind+"/"+candidate.ConfigName)
 			}
+			finding := location.finding(utils.SeverityWarning, utils.CodeEnvAmbiguous, envVar, envName)
+			finding.Message = fmt.Sprintf("环境变量 %s 有多个配置来源 %s (策略: %s, %s)，已使用 %s/%s",
+				envName, strings.Join(candidateNames, ", "), resolution.Strategy,
+				location, resolution.ConfigKind, resolution.ConfigName)
+			finding.Suggestion = "使用--resolvers调整解析策略的顺序，或显式设置valueFrom"
+			p.Report.Add(finding)
+		}
 
-			// 从缓存中查找配置值
-			_, configName, configKind, found := FindConfigValue(envName, namespace, p.ConfigCache)
-			if found {
-				// 根据类型创建valueFrom引用
-				valueFrom := make(map[string]interface{})
-
-				if configKind == utils.ConfigMapKind {
-					configMapKeyRef := map[string]interface{}{
-						"name": configName,
-						"key":  envName,
-					}
-					valueFrom["configMapKeyRef"] = configMapKeyRef
-				} else if configKind == utils.SecretKind {
-					secretKeyRef := map[string]interface{}{
-						"name": configName,
-						"key":  envName,
-					}
-					valueFrom["secretKeyRef"] = secretKeyRef
-				}
-
-				// 更新环境变量
-				envVarMap["valueFrom"] = valueFrom
-				envList[i] = envVarMap
-				modified = true
-			} else {
-				// 未找到配置，添加警告注释
-				p.Report.Warnings = append(p.Report.Warnings,
-					fmt.Sprintf("未找到环境变量 %s 的配置 (资源: %s/%s)",
-						envName, namespace, resourceName))
+		groupKey := resolution.ConfigKind + "/" + resolution.ConfigName
+		if _, exists := groups[groupKey]; !exists {
+			groupOrder = append(groupOrder, groupKey)
+		}
+		groups[groupKey] = append(groups[groupKey], &pendingEnv{node: envVar, name: envName, resolution: resolution})
+	}
+
+	modified := false
+	removed := make(map[*yaml.Node]bool)
+
+	for _, groupKey := range groupOrder {
+		group := groups[groupKey]
+		resolution := group[0].resolution
+
+		// 合并为envFrom
+		if prefix, ok := p.collapseToEnvFrom(group, namespace); ok {
+			envFrom := parser.MappingValue(container, "envFrom")
+			if envFrom == nil || envFrom.Kind != yaml.SequenceNode {
+				envFrom = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				parser.SetMappingValue(container, "envFrom", envFrom)
+			}
+			envFrom.Content = append(envFrom.Content,
+				newEnvFromSource(resolution.ConfigKind, resolution.ConfigName, prefix))
+
+			for _, pending := range group {
+				removed[pending.node] = true
 			}
+			modified = true
+
+			finding := location.finding(utils.SeverityInfo, utils.CodeEnvCollapsed, group[0].node, strings.Join(pendingNames(group), ","))
+			finding.Message = fmt.Sprintf("环境变量 %s 合并为envFrom引用 %s/%s (%s)",
+				strings.Join(pendingNames(group), ", "), resolution.ConfigKind, resolution.ConfigName, location)
+			p.Report.Add(finding)
+			continue
+		}
+
+		// 逐个创建valueFrom引用
+		for _, pending := range group {
+			p.setKeyRef(pending, location)
 		}
+		modified = true
 	}
 
-	// 如果有修改，更新环境变量列表
-	if modified {
-		container["env"] = envList
+	// 已由envFrom提供的空定义会以空值覆盖envFrom，需要移除或改为显式引用
+	for _, pending := range satisfied {
+		if p.EnvStyle == utils.EnvStyleKeyRef {
+			p.setKeyRef(pending, location)
+		} else {
+			removed[pending.node] = true
+			finding := location.finding(utils.SeverityInfo, utils.CodeEnvRemoved, pending.node, pending.name)
+			finding.Message = fmt.Sprintf("环境变量 %s 已由envFrom引用 %s/%s 提供，移除空定义 (%s)",
+				pending.name, pending.resolution.ConfigKind, pending.resolution.ConfigName, location)
+			p.Report.Add(finding)
+		}
+		modified = true
+	}
+
+	// 移除已合并的环境变量
+	if len(removed) > 0 {
+		var remaining []*yaml.Node
+		for _, envVar := range env.Content {
+			if !removed[envVar] {
+				remaining = append(remaining, envVar)
+			}
+		}
+
+		if len(remaining) == 0 {
+			parser.DeleteMappingValue(container, "env")
+		} else {
+			env.Content = remaining
+		}
 	}
 
 	return modified, nil
 }
+
+// 判断一组环境变量是否按当前风格合并为envFrom
+func (p *WorkloadProcessor) collapseToEnvFrom(group []*pendingEnv, namespace string) (string, bool) {
+	switch p.EnvStyle {
+	case utils.EnvStyleEnvFrom:
+	case utils.EnvStyleAuto:
+		if len(group) < utils.AutoEnvFromThreshold {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	resolution := group[0].resolution
+	data, exists := cacheObjects(p.ConfigCache, resolution.ConfigKind)[namespace][resolution.ConfigName]
+	if !exists {
+		return "", false
+	}
+
+	return envFromPrefix(group, data)
+}
+
+// 为环境变量创建valueFrom引用
+func (p *WorkloadProcessor) setKeyRef(pending *pendingEnv, location ContainerLocation) {
+	resolution := pending.resolution
+	parser.SetMappingValue(pending.node, "valueFrom",
+		newValueFrom(resolution.ConfigKind, resolution.ConfigName, resolution.Key))
+
+	finding := location.finding(utils.SeverityInfo, utils.CodeEnvResolved, pending.node, pending.name)
+	finding.Message = fmt.Sprintf("环境变量 %s 引用 %s/%s (%s)", pending.name, resolution.ConfigKind, resolution.ConfigName, location)
+	p.Report.Add(finding)
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 初始化容器、原生sidecar和临时容器中的环境变量与普通容器一样填充，报告中标明所在的容器列表
+func TestProcessAllContainerLists(t *testing.T) {
+	pod := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  initContainers:
+    - name: migrate
+      env:
+        - name: LOG_LEVEL
+    - name: proxy
+      restartPolicy: Always
+      env:
+        - name: PORT
+  containers:
+    - name: web
+      env:
+        - name: LOG_LEVEL
+  ephemeralContainers:
+    - name: debug
+      env:
+        - name: PORT
+`
+	p, _ := newTestProcessor(t, nil)
+	output, err := p.ProcessContent(context.Background(), "pod.yaml", []byte(testConfigMap+"---\n"+pod))
+	if err != nil {
+		t.Fatalf("ProcessContent: %v", err)
+	}
+	if count := strings.Count(string(output), "configMapKeyRef:"); count != 4 {
+		t.Errorf("应填充4个环境变量, 实际 %d:\n%s", count, output)
+	}
+
+	var messages []string
+	for _, finding := range p.Report.Findings {
+		if finding.Code == utils.CodeEnvResolved {
+			messages = append(messages, finding.Message)
+		}
+	}
+	for _, want := range []string{
+		"initContainers: migrate)",
+		"initContainers(sidecar): proxy)",
+		"containers: web)",
+		"ephemeralContainers: debug)",
+	} {
+		found := false
+		for _, message := range messages {
+			if strings.HasSuffix(message, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("报告中缺少容器 %q:\n%s", want, strings.Join(messages, "\n"))
+		}
+	}
+}
+
+// env为别名等非列表节点时整个资源保持不变，不留下其他容器的部分修改和报告条目
+func TestProcessAliasedEnv(t *testing.T) {
+	pod := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+    - name: web
+      env: &shared
+        - name: LOG_LEVEL
+  initContainers:
+    - name: migrate
+      env: *shared
+`
+	input := testConfigMap + "---\n" + pod
+	p, _ := newTestProcessor(t, nil)
+	output, err := p.ProcessContent(context.Background(), "pod.yaml", []byte(input))
+	if err != nil {
+		t.Fatalf("ProcessContent: %v", err)
+	}
+	if string(output) != input {
+		t.Errorf("格式无效的资源不应被修改:\n%s", output)
+	}
+
+	var codes []string
+	for _, finding := range p.Report.Findings {
+		codes = append(codes, finding.Code)
+	}
+	if len(codes) != 1 || codes[0] != utils.CodeProcessError {
+		t.Errorf("报告条目 = %v, 期望只有 %s", codes, utils.CodeProcessError)
+	}
+	if message := p.Report.Findings[0].Message; !strings.Contains(message, "initContainers: migrate 的env字段格式无效") {
+		t.Errorf("错误信息应指出别名所在的容器: %s", message)
+	}
+}
@@ -0,0 +1,335 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 工具名称，用于SARIF和JUnit报告
+const toolName = "k8sconfig-processor"
+
+// 按格式输出处理报告
+func Write(w io.Writer, format string, report *utils.ProcessReport) error {
+	switch format {
+	case utils.ReportFormatJSON:
+		return WriteJSON(w, report)
+	case utils.ReportFormatSARIF:
+		return WriteSARIF(w, report)
+	case utils.ReportFormatJUnit:
+		return WriteJUnit(w, report)
+	case utils.ReportFormatText, "":
+		return WriteText(w, report)
+	default:
+		return fmt.Errorf("未知的报告格式: %s", format)
+	}
+}
+
+// 输出文本报告
+func WriteText(w io.Writer, report *utils.ProcessReport) error {
+	fmt.Fprintln(w, "\n===== 处理报告 =====")
+	fmt.Fprintf(w, "文件总数: %d\n", report.TotalFiles)
+	fmt.Fprintf(w, "处理的文件数: %d\n", report.ProcessedFiles)
+	fmt.Fprintf(w, "成功更新的资源数: %d\n", report.SuccessfulUpdates)
+
+	if len(report.Changes) > 0 {
+		fmt.Fprintln(w, "\n变更:")
+		for _, change := range report.Changes {
+			fmt.Fprintf(w, "- %s\n", change)
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Fprintln(w, "\n警告:")
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(w, "- %s\n", warning)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		fmt.Fprintln(w, "\n错误:")
+		for _, err := range report.Errors {
+			fmt.Fprintf(w, "- %s\n", err)
+		}
+	}
+
+	return nil
+}
+
+// JSON报告
+type jsonReport struct {
+	TotalFiles        int             `json:"totalFiles"`
+	ProcessedFiles    int             `json:"processedFiles"`
+	SuccessfulUpdates int             `json:"successfulUpdates"`
+	Findings          []utils.Finding `json:"findings"`
+}
+
+// 输出JSON报告
+func WriteJSON(w io.Writer, report *utils.ProcessReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonReport{
+		TotalFiles:        report.TotalFiles,
+		ProcessedFiles:    report.ProcessedFiles,
+		SuccessfulUpdates: report.SuccessfulUpdates,
+		Findings:          report.Findings,
+	})
+}
+
+// SARIF 2.1.0报告的结构
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// 报告代码的说明，用于SARIF规则
+var codeDescriptions = map[string]string{
+	utils.CodeEnvUnresolved:     "未找到环境变量的配置",
+	utils.CodeEnvAmbiguous:      "多个配置对象提供同一个键",
+	utils.CodeMissingPodSpec:    "工作负载缺少pod规格",
+	utils.CodeMissingContainers: "pod规格缺少containers字段",
+	utils.CodeMissingReference:  "引用的配置对象或键不存在",
+	utils.CodeParseError:        "文件无法解析",
+	utils.CodeInvalidConfig:     "配置对象内容无效",
+	utils.CodeProcessError:      "处理资源失败",
+	utils.CodeOverlayConflict:   "基础资源在不同overlay中的解析结果不同",
+	utils.CodeChecksumSkipped:   "只有键的Secret无法计算配置哈希",
+	utils.CodeExternalizeSkip:   "环境变量的字面值无法移至配置对象",
+	utils.CodeStubCreated:       "环境变量引用了值为TODO的占位配置对象",
+	utils.CodeHardcodedSecret:   "清单中的字面值像硬编码的密钥",
+	utils.CodeSecretInConfigMap: "敏感信息存放在ConfigMap而不是Secret中",
+}
+
+// 输出SARIF报告，只包含警告和错误，已执行的变更不作为代码扫描结果
+func WriteSARIF(w io.Writer, report *utils.ProcessReport) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: toolName}},
+		Results: make([]sarifResult, 0),
+	}
+
+	rules := make(map[string]bool)
+	for _, finding := range report.Findings {
+		if finding.Severity == utils.SeverityInfo {
+			continue
+		}
+		rules[finding.Code] = true
+
+		result := sarifResult{
+			RuleID:  finding.Code,
+			Level:   finding.Severity,
+			Message: sarifMessage{Text: finding.Message},
+		}
+		if finding.Suggestion != "" {
+			result.Message.Text += "。建议: " + finding.Suggestion
+		}
+		if finding.File != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(finding.File)},
+			}}
+			if finding.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		result.Properties = findingProperties(finding)
+		run.Results = append(run.Results, result)
+	}
+
+	// 规则按代码排序，保证输出稳定
+	var codes []string
+	for code := range rules {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	run.Tool.Driver.Rules = make([]sarifRule, 0, len(codes))
+	for _, code := range codes {
+		description := codeDescriptions[code]
+		if description == "" {
+			description = code
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               code,
+			ShortDescription: sarifMessage{Text: description},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// 发现中的资源信息，作为SARIF结果的属性
+func findingProperties(finding utils.Finding) map[string]string {
+	properties := make(map[string]string)
+	for key, value := range map[string]string{
+		"namespace":  finding.Namespace,
+		"workload":   finding.Workload,
+		"container":  finding.Container,
+		"envVar":     finding.EnvVar,
+		"suggestion": finding.Suggestion,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return properties
+}
+
+// JUnit报告的结构
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// 输出JUnit报告：每个文件一个测试套件，每条警告或错误一个失败的测试用例
+func WriteJUnit(w io.Writer, report *utils.ProcessReport) error {
+	suites := junitTestSuites{Name: toolName}
+	index := make(map[string]int)
+
+	for _, finding := range report.Findings {
+		if finding.Severity == utils.SeverityInfo {
+			continue
+		}
+
+		file := filepath.ToSlash(finding.File)
+		if file == "" {
+			file = toolName
+		}
+		position, exists := index[file]
+		if !exists {
+			position = len(suites.Suites)
+			index[file] = position
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: file})
+		}
+		suite := &suites.Suites[position]
+
+		detail := finding.String()
+		if finding.Suggestion != "" {
+			detail += "\n建议: " + finding.Suggestion
+		}
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("%s: %s", finding.Code, finding.Message),
+			ClassName: file,
+		}
+		failure := &junitFailure{Message: finding.Message, Type: finding.Code, Text: detail}
+		if finding.Severity == utils.SeverityError {
+			testCase.Error = failure
+			suite.Errors++
+			suites.Errors++
+		} else {
+			testCase.Failure = failure
+			suite.Failures++
+			suites.Failures++
+		}
+		suite.Tests++
+		suites.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	// 没有问题时输出一个通过的测试用例，便于CI显示结果
+	if len(suites.Suites) == 0 {
+		suites.Suites = append(suites.Suites, junitTestSuite{
+			Name:      toolName,
+			Tests:     1,
+			TestCases: []junitTestCase{{Name: "所有环境变量均已解析", ClassName: toolName}},
+		})
+		suites.Tests = 1
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,189 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k8sconfig-processor/pkg/utils"
+)
+
+// 使用 go test ./pkg/report -update 重新生成期望输出
+var update = flag.Bool("update", false, "重新生成testdata中的期望输出")
+
+// 包含变更、警告、错误、没有文件和没有行号的发现的报告，规则代码不按字母顺序出现
+func testReport() *utils.ProcessReport {
+	report := utils.NewProcessReport()
+	report.TotalFiles = 3
+	report.ProcessedFiles = 1
+	report.SuccessfulUpdates = 1
+	for _, finding := range []utils.Finding{
+		{
+			Severity: utils.SeverityInfo, Code: utils.CodeEnvResolved, File: "apps/web.yaml", Line: 12,
+			Namespace: "default", Workload: "web", Container: "web", EnvVar: "LOG_LEVEL",
+			Message: "环境变量 LOG_LEVEL 引用 ConfigMap app-config",
+		},
+		{
+			Severity: utils.SeverityWarning, Code: utils.CodeEnvUnresolved, File: "apps/web.yaml", Line: 14,
+			Namespace: "default", Workload: "web", Container: "web", EnvVar: "REGION",
+			Message: "未找到环境变量 REGION 的配置", Suggestion: "创建名为region的ConfigMap",
+		},
+		{
+			Severity: utils.SeverityError, Code: utils.CodeMissingReference, File: "apps/worker.yaml", Line: 20,
+			Namespace: "jobs", Workload: "worker", EnvVar: "DB_HOST",
+			Message: "引用的ConfigMap db-config不存在",
+		},
+		{
+			Severity: utils.SeverityError, Code: utils.CodeParseError, File: "broken.yaml",
+			Message: "解析文件失败: yaml: line 2: did not find expected node content",
+		},
+		{
+			Severity: utils.SeverityWarning, Code: "custom-code",
+			Message: "没有文件的发现",
+		},
+	} {
+		report.Add(finding)
+	}
+	return report
+}
+
+// 对比输出与testdata中的期望输出
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("读取期望输出失败: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s 与期望输出不一致:\n%s", name, got)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "report.sarif", buf.Bytes())
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF不是有效的JSON: %v", err)
+	}
+	run := log.Runs[0]
+
+	// 规则按代码排序，只包含出现的警告和错误
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID)
+	}
+	wantRules := []string{"custom-code", utils.CodeEnvUnresolved, utils.CodeMissingReference, utils.CodeParseError}
+	if len(rules) != len(wantRules) {
+		t.Fatalf("规则 = %v, 期望 %v", rules, wantRules)
+	}
+	for i := range rules {
+		if rules[i] != wantRules[i] {
+			t.Errorf("规则 = %v, 期望 %v", rules, wantRules)
+			break
+		}
+	}
+
+	// 变更不作为结果；级别、规则和位置与发现对应
+	if len(run.Results) != 4 {
+		t.Fatalf("结果数 = %d, 期望 4", len(run.Results))
+	}
+	missing := run.Results[1]
+	if missing.RuleID != utils.CodeMissingReference || missing.Level != "error" {
+		t.Errorf("结果 = %+v, 期望 missing-reference 的 error", missing)
+	}
+	location := missing.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "apps/worker.yaml" || location.Region == nil || location.Region.StartLine != 20 {
+		t.Errorf("位置 = %+v, 期望 apps/worker.yaml 第20行", location)
+	}
+	if region := run.Results[2].Locations[0].PhysicalLocation.Region; region != nil {
+		t.Errorf("没有行号的发现不应有region: %+v", region)
+	}
+	if locations := run.Results[3].Locations; len(locations) != 0 {
+		t.Errorf("没有文件的发现不应有位置: %+v", locations)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   *utils.ProcessReport
+		golden   string
+		tests    int
+		failures int
+		errors   int
+		suites   int
+	}{
+		// 每个文件一个测试套件，警告为failure，错误为error
+		{"有发现", testReport(), "report.junit.xml", 4, 2, 2, 4},
+		// 只有变更时输出一个通过的测试用例
+		{"没有发现", func() *utils.ProcessReport {
+			report := utils.NewProcessReport()
+			report.Add(utils.Finding{Severity: utils.SeverityInfo, Code: utils.CodeEnvResolved, Message: "已解析"})
+			return report
+		}(), "empty.junit.xml", 1, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteJUnit(&buf, tt.report); err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tt.golden, buf.Bytes())
+
+			var suites junitTestSuites
+			if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+				t.Fatalf("JUnit不是有效的XML: %v", err)
+			}
+			if suites.Tests != tt.tests || suites.Failures != tt.failures || suites.Errors != tt.errors || len(suites.Suites) != tt.suites {
+				t.Errorf("tests=%d failures=%d errors=%d suites=%d, 期望 %d %d %d %d",
+					suites.Tests, suites.Failures, suites.Errors, len(suites.Suites), tt.tests, tt.failures, tt.errors, tt.suites)
+			}
+
+			// 套件的计数与其中的测试用例一致
+			for _, suite := range suites.Suites {
+				failures, errors := 0, 0
+				for _, testCase := range suite.TestCases {
+					if testCase.Failure != nil {
+						failures++
+					}
+					if testCase.Error != nil {
+						errors++
+					}
+				}
+				if suite.Tests != len(suite.TestCases) || suite.Failures != failures || suite.Errors != errors {
+					t.Errorf("套件 %s 的计数与测试用例不一致: %+v", suite.Name, suite)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, utils.ReportFormatJSON, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "report.json", buf.Bytes())
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", testReport()); err == nil {
+		t.Error("未知的报告格式应返回错误")
+	}
+}
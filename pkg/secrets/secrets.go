@@ -0,0 +1,200 @@
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 默认的敏感名称模式：名称按下划线分段后，短模式需与某一段相同，长模式只需出现在名称中
+var DefaultNamePatterns = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL", "PRIVATE"}
+
+const (
+	// 按子串匹配的模式的最小长度，更短的模式按分段匹配，避免MONKEY匹配KEY
+	substringPatternLength = 6
+
+	// 视为随机字符串的最小长度
+	MinRandomLength = 16
+	// 视为随机字符串的最小香农熵（比特/字符）
+	MinEntropy = 3.5
+)
+
+// 检测规则
+const (
+	RuleFormat  = "format"  // 值符合已知的令牌格式
+	RuleName    = "name"    // 名称匹配敏感名称模式
+	RuleEntropy = "entropy" // 值像随机生成的字符串
+)
+
+// 已知的令牌格式，值中出现即视为密钥
+var tokenFormats = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"私钥", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]*`)},
+	{"AWS访问密钥", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub令牌", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack令牌", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"Google API密钥", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"URL中的密码", regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// 不会是密钥的占位值
+var placeholderValues = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"null": true, "none": true, "todo": true,
+}
+
+// 主机名和文件路径，虽然较长且混合字母数字，但不是密钥
+var (
+	hostnamePattern = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)+(:[0-9]+)?$`)
+	pathPattern     = regexp.MustCompile(`^(/[A-Za-z0-9._-]+)+/?$`)
+)
+
+// 判断名称是否匹配敏感名称模式，模式为空时使用默认模式
+func MatchesName(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		patterns = DefaultNamePatterns
+	}
+
+	upper := strings.ToUpper(name)
+	segments := strings.FieldsFunc(upper, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+
+	for _, pattern := range patterns {
+		pattern = strings.ToUpper(pattern)
+		if len(pattern) >= substringPatternLength && strings.Contains(upper, pattern) {
+			return true
+		}
+		for _, segment := range segments {
+			if segment == pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 计算字符串的香农熵（比特/字符）
+func Entropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// 判断值是否像随机生成的密钥：足够长、混合多种字符、熵足够高，且不是URL、主机名或路径
+func LooksRandom(value string) bool {
+	if len(value) < MinRandomLength || strings.ContainsAny(value, " \t\n") || strings.Contains(value, "://") {
+		return false
+	}
+	if hostnamePattern.MatchString(value) || pathPattern.MatchString(value) {
+		return false
+	}
+
+	var lower, upper, digit, other bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+
+	return classes >= 2 && Entropy(value) >= MinEntropy
+}
+
+// 判断环境变量是否应作为Secret处理：Detect检测到的值都视为密钥，与scan命令一致；
+// 名称匹配敏感模式时即使是占位值也视为密钥，以后填入的实际值不会落在ConfigMap中
+func IsSecret(name, value string, patterns []string) bool {
+	if MatchesName(name, patterns) {
+		return true
+	}
+	_, found := Detect(name, value, patterns)
+	return found
+}
+
+// 返回值中出现的已知令牌格式名称
+func TokenFormat(value string) (string, bool) {
+	for _, format := range tokenFormats {
+		if format.pattern.MatchString(value) {
+			return format.name, true
+		}
+	}
+	return "", false
+}
+
+// 判断值是否明显不是密钥：布尔值、数字、TODO等占位符，以及$(VAR)和${VAR}引用
+func IsPlaceholder(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || placeholderValues[strings.ToLower(value)] {
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	return strings.HasPrefix(value, "$(") || strings.HasPrefix(value, "${")
+}
+
+// 疑似密钥的检测结果
+type Detection struct {
+	// 命中的规则: format, name, entropy
+	Rule string
+	// 说明，例如令牌格式名称或熵
+	Detail string
+}
+
+// 检测名称和值是否像硬编码的密钥，依次检查已知格式、名称模式和熵；name为空时只检查值
+func Detect(name, value string, patterns []string) (Detection, bool) {
+	if format, ok := TokenFormat(value); ok {
+		return Detection{Rule: RuleFormat, Detail: format}, true
+	}
+	if IsPlaceholder(value) {
+		return Detection{}, false
+	}
+	if name != "" && MatchesName(name, patterns) {
+		return Detection{Rule: RuleName, Detail: "名称匹配敏感模式"}, true
+	}
+	if LooksRandom(value) {
+		return Detection{Rule: RuleEntropy, Detail: fmt.Sprintf("熵为 %.1f 比特/字符", Entropy(value))}, true
+	}
+	return Detection{}, false
+}
+
+// 遮盖值用于报告，只保留开头的少量字符
+func Mask(value string) string {
+	runes := []rune(value)
+	if len(runes) < 8 {
+		return "****"
+	}
+	return string(runes[:2]) + "****"
+}
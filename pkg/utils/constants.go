@@ -6,15 +6,43 @@ const (
 	DeploymentKind  = "Deployment"
 	StatefulSetKind = "StatefulSet"
 	DaemonSetKind   = "DaemonSet"
+	ReplicaSetKind  = "ReplicaSet"
+	JobKind         = "Job"
+	CronJobKind     = "CronJob"
+	PodKind         = "Pod"
+	PodTemplateKind = "PodTemplate"
+
+	ReplicationControllerKind = "ReplicationController"
 
 	// 配置资源类型
 	ConfigMapKind = "ConfigMap"
 	SecretKind    = "Secret"
 
+	// 配置数据字段
+	DataField       = "data"
+	StringDataField = "stringData"
+	BinaryDataField = "binaryData"
+
+	// 容器列表字段
+	ContainersField          = "containers"
+	InitContainersField      = "initContainers"
+	EphemeralContainersField = "ephemeralContainers"
+
 	// YAML文件扩展名
 	YamlExt = ".yaml"
 	YmlExt  = ".yml"
 
+	// 没有扩展名的kustomization文件，扫描时与YAML文件一同读取
+	KustomizationFile = "Kustomization"
+
+	// 忽略文件，语法与.gitignore相同
+	GitIgnoreFile       = ".gitignore"
+	K8sConfigIgnoreFile = ".k8sconfigignore"
+
+	// 配置来源的默认优先级；输入目录的优先级更高，服务仓库中的配置可以覆盖共享配置
+	DefaultSourcePriority = 0
+	InputSourcePriority   = 100
+
 	// 默认命名空间
 	DefaultNamespace = "default"
 
@@ -25,4 +53,114 @@ const (
 
 	// 输出目录
 	DefaultOutputDir = "./processed"
+
+	// 流模式中表示标准输入的文件名，以及报告中使用的名称
+	StdinFile = "-"
+	StdinName = "<stdin>"
+
+	// 干运行模式的差异格式
+	DiffFormatUnified    = "unified"      // git风格的统一差异
+	DiffFormatSideBySide = "side-by-side" // 左右对照
+	DiffFormatJSONPatch  = "json-patch"   // 每个文档的RFC 6902 JSON Patch
+
+	// 退出码
+	ExitClean    = 0 // 没有变更、警告和错误
+	ExitFailure  = 1 // 执行失败，例如选项无效或预检查未通过
+	ExitChanges  = 2 // 有变更
+	ExitWarnings = 3 // 有警告
+	ExitErrors   = 4 // 有错误
+
+	// 以非零退出码结束的条件，级别依次升高
+	FailOnChange  = "change"  // 有变更、警告或错误
+	FailOnWarning = "warning" // 有警告或错误
+	FailOnError   = "error"   // 有错误
+
+	// 文件中有无法解析的文档时的处理策略
+	ParseErrorKeep = "keep" // 原样保留无法解析的文档，继续处理其余文档
+	ParseErrorSkip = "skip" // 跳过整个文件，不写入任何内容
+
+	// 报告格式
+	ReportFormatText  = "text"
+	ReportFormatJSON  = "json"
+	ReportFormatSARIF = "sarif"
+	ReportFormatJUnit = "junit"
+
+	// 报告级别
+	SeverityInfo    = "info"    // 已执行的变更
+	SeverityWarning = "warning" // 需要关注但不影响输出
+	SeverityError   = "error"   // 处理失败或引用无效
+
+	// 报告代码
+	CodeEnvResolved       = "env-resolved"        // 环境变量已引用配置对象
+	CodeEnvCollapsed      = "env-collapsed"       // 环境变量已合并为envFrom
+	CodeEnvRemoved        = "env-removed"         // 已由envFrom提供的空定义被移除
+	CodeEnvUnresolved     = "env-unresolved"      // 未找到环境变量的配置
+	CodeEnvAmbiguous      = "env-ambiguous"       // 多个配置对象提供同一个键
+	CodeMissingPodSpec    = "missing-pod-spec"    // 工作负载缺少pod规格
+	CodeMissingContainers = "missing-containers"  // pod规格缺少containers
+	CodeMissingReference  = "missing-reference"   // 引用的配置对象或键不存在
+	CodeParseError        = "parse-error"         // 文件无法解析
+	CodeInvalidConfig     = "invalid-config"      // 配置对象内容无效
+	CodeProcessError      = "process-error"       // 处理资源失败
+	CodeFileSkipped       = "file-skipped"        // 文件因包含无法解析的文档被跳过
+	CodeOverlayConflict   = "overlay-conflict"    // 基础资源在不同overlay中的解析结果不同
+	CodeChecksumUpdated   = "checksum-updated"    // pod模板的配置哈希注解已更新
+	CodeChecksumRemoved   = "checksum-removed"    // 不再引用的配置对象的哈希注解已删除
+	CodeChecksumSkipped   = "checksum-skipped"    // 只有键的Secret无法计算哈希
+	CodeConfigVersioned   = "config-versioned"    // 配置对象已重命名为带内容哈希的名称
+	CodeReferenceRewrite  = "reference-rewrite"   // 引用已改写为新的配置对象名称
+	CodeEnvExternalized   = "env-externalized"    // 环境变量的字面值已移至配置对象
+	CodeExternalizeSkip   = "externalize-skipped" // 字面值无法移至配置对象
+	CodeStubCreated       = "stub-created"        // 环境变量引用了新生成的占位配置对象
+	CodeHardcodedSecret   = "hardcoded-secret"    // 清单中的字面值像硬编码的密钥
+	CodeSecretInConfigMap = "secret-in-configmap" // 敏感信息存放在ConfigMap而不是Secret中
+
+	// pod模板上配置哈希注解的前缀，后接配置对象名称
+	ChecksumAnnotationPrefix = "checksum/"
+
+	// pod模板上记录本工具写入的checksum/注解的注解，值为逗号分隔的注解键；只有其中列出的注解会被删除
+	ChecksumOwnerAnnotation = "k8sconfig-processor/checksums"
+
+	// 注解键中前缀之后的名称部分的最大长度，超出时截断并附加名称的哈希
+	MaxAnnotationNameLength = 63
+
+	// 带内容哈希的配置对象上记录原始名称的注解
+	BaseNameAnnotation = "k8sconfig-processor/base-name"
+
+	// kubectl apply记录的上一次配置，内容为整个清单，扫描密钥时跳过
+	LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+	// externalize生成的配置清单的默认文件名，相对于输入目录
+	ExternalizedFile = "externalized-config.yaml"
+
+	// --create-missing生成的占位配置清单的文件名，相对于输入目录，以及占位值
+	StubsFile = "missing-config.yaml"
+	StubValue = "TODO"
+
+	// 差异默认的上下文行数
+	DefaultDiffContext = 3
+
+	// 左右对照时每栏的宽度
+	SideBySideWidth = 60
+
+	// 环境变量引用风格
+	EnvStyleKeyRef  = "keyRef"  // 每个环境变量单独使用valueFrom引用
+	EnvStyleEnvFrom = "envFrom" // 配置对象的全部键都被需要时合并为envFrom
+	EnvStyleAuto    = "auto"    // 满足envFrom条件且变量数达到阈值时合并
+
+	// auto风格下合并为envFrom所需的最少环境变量数
+	AutoEnvFromThreshold = 3
+
+	// 名称解析策略
+	ResolverExact    = "exact"    // 配置对象名称等于环境变量名的小写形式
+	ResolverWorkload = "workload" // <工作负载>-config / <工作负载>-secret
+	ResolverPrefix   = "prefix"   // 按前缀规则映射，例如DB_=db-config
+	ResolverKey      = "key"      // 命名空间内任意包含该键的配置对象
+	ResolverEnvFrom  = "envFrom"  // 已由容器现有的envFrom提供，不能通过--resolvers选择
 )
+
+// 默认的解析策略顺序
+var DefaultResolvers = []string{ResolverExact, ResolverWorkload, ResolverPrefix, ResolverKey}
+
+// 扫描时默认跳过的目录
+var DefaultIgnoredDirs = []string{".git", "node_modules", "vendor"}
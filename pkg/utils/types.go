@@ -1,10 +1,25 @@
 package utils
 
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
 // 配置对象缓存
 type ConfigCache struct {
-	// 按类型存储的配置缓存: map[资源类型][namespace][name]map[key]value
+	// 按类型存储的配置缓存: map[namespace][name]map[key]value，Secret中的值已解码
 	ConfigMaps map[string]map[string]map[string]string
 	Secrets    map[string]map[string]map[string]string
+
+	// ConfigMap的binaryData，已解码，不能被环境变量引用: map[namespace][name]map[key]value
+	BinaryData map[string]map[string]map[string]string
+
+	// 每个键的来源字段: map[资源类型][namespace][name]map[key]字段名
+	KeyFields map[string]map[string]map[string]map[string]string
+
+	// 只读取了键、没有值的Secret: map[namespace]map[name]bool
+	KeysOnly map[string]map[string]bool
 }
 
 // 新建配置缓存
@@ -12,9 +27,53 @@ func NewConfigCache() *ConfigCache {
 	return &ConfigCache{
 		ConfigMaps: make(map[string]map[string]map[string]string),
 		Secrets:    make(map[string]map[string]map[string]string),
+		BinaryData: make(map[string]map[string]map[string]string),
+		KeyFields:  make(map[string]map[string]map[string]map[string]string),
+		KeysOnly:   make(map[string]map[string]bool),
 	}
 }
 
+// 记录键的来源字段（data, stringData或binaryData）
+func (c *ConfigCache) SetKeyField(kind, namespace, name, key, field string) {
+	if _, exists := c.KeyFields[kind]; !exists {
+		c.KeyFields[kind] = make(map[string]map[string]map[string]string)
+	}
+	if _, exists := c.KeyFields[kind][namespace]; !exists {
+		c.KeyFields[kind][namespace] = make(map[string]map[string]string)
+	}
+	if _, exists := c.KeyFields[kind][namespace][name]; !exists {
+		c.KeyFields[kind][namespace][name] = make(map[string]string)
+	}
+	c.KeyFields[kind][namespace][name][key] = field
+}
+
+// 清除对象的键来源字段，对象被其他来源中的同名对象替换时使用
+func (c *ConfigCache) ResetKeyFields(kind, namespace, name string) {
+	delete(c.KeyFields[kind][namespace], name)
+}
+
+// 获取键的来源字段
+func (c *ConfigCache) KeyField(kind, namespace, name, key string) string {
+	return c.KeyFields[kind][namespace][name][key]
+}
+
+// 记录Secret是否只有键，对象被其他来源中的同名对象替换时一并更新
+func (c *ConfigCache) SetKeysOnly(namespace, name string, keysOnly bool) {
+	if !keysOnly {
+		delete(c.KeysOnly[namespace], name)
+		return
+	}
+	if _, exists := c.KeysOnly[namespace]; !exists {
+		c.KeysOnly[namespace] = make(map[string]bool)
+	}
+	c.KeysOnly[namespace][name] = true
+}
+
+// 判断Secret是否只有键，没有值
+func (c *ConfigCache) IsKeysOnly(namespace, name string) bool {
+	return c.KeysOnly[namespace][name]
+}
+
 // 处理报告
 type ProcessReport struct {
 	// 处理统计
@@ -22,35 +81,255 @@ type ProcessReport struct {
 	ProcessedFiles    int
 	SuccessfulUpdates int
 
-	// 警告和错误
+	// 变更、警告和错误
+	Changes  []string
 	Warnings []string
 	Errors   []string
+
+	// 结构化的发现，与上面的文本一一对应
+	Findings []Finding
+
+	// 保护并发添加
+	mu sync.Mutex
 }
 
 // 新建处理报告
 func NewProcessReport() *ProcessReport {
 	return &ProcessReport{
+		Changes:  make([]string, 0),
 		Warnings: make([]string, 0),
 		Errors:   make([]string, 0),
+		Findings: make([]Finding, 0),
 	}
 }
 
+// 添加一条发现，同时按级别记录文本，可以并发调用
+func (r *ProcessReport) Add(finding Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.addLocked(finding)
+}
+
+// 在已持有锁时添加发现
+func (r *ProcessReport) addLocked(finding Finding) {
+	r.Findings = append(r.Findings, finding)
+
+	text := finding.String()
+	switch finding.Severity {
+	case SeverityError:
+		r.Errors = append(r.Errors, text)
+	case SeverityWarning:
+		r.Warnings = append(r.Warnings, text)
+	default:
+		r.Changes = append(r.Changes, text)
+	}
+}
+
+// 合并另一份报告的统计和发现
+func (r *ProcessReport) Merge(other *ProcessReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ProcessedFiles += other.ProcessedFiles
+	r.SuccessfulUpdates += other.SuccessfulUpdates
+	for _, finding := range other.Findings {
+		r.addLocked(finding)
+	}
+}
+
+// 按文件、行号和代码排序所有发现，使输出与处理顺序无关
+func (r *ProcessReport) Sort() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	findings := r.Findings
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Message < b.Message
+	})
+
+	r.Findings = make([]Finding, 0, len(findings))
+	r.Changes = r.Changes[:0]
+	r.Warnings = r.Warnings[:0]
+	r.Errors = r.Errors[:0]
+	for _, finding := range findings {
+		r.addLocked(finding)
+	}
+}
+
+// 根据报告内容和失败条件计算退出码，未达到失败条件时返回ExitClean
+func (r *ProcessReport) ExitCode(failOn string) int {
+	levels := map[string]int{FailOnChange: 1, FailOnWarning: 2, FailOnError: 3}
+
+	code, level := ExitClean, 0
+	switch {
+	case len(r.Errors) > 0:
+		code, level = ExitErrors, 3
+	case len(r.Warnings) > 0:
+		code, level = ExitWarnings, 2
+	case len(r.Changes) > 0:
+		code, level = ExitChanges, 1
+	}
+
+	threshold, exists := levels[failOn]
+	if !exists {
+		threshold = levels[FailOnError]
+	}
+	if level < threshold {
+		return ExitClean
+	}
+	return code
+}
+
+// 报告中的单条发现
+type Finding struct {
+	// 级别: info（变更）, warning, error
+	Severity string `json:"severity"`
+	// 问题代码
+	Code string `json:"code"`
+	// 文件路径及行号
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// 所在的资源和容器
+	Namespace string `json:"namespace,omitempty"`
+	Workload  string `json:"workload,omitempty"`
+	Container string `json:"container,omitempty"`
+	// 相关的环境变量
+	EnvVar string `json:"envVar,omitempty"`
+	// 描述
+	Message string `json:"message"`
+	// 建议的修复方式
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// 格式化为文本报告中的一行
+func (f Finding) String() string {
+	switch {
+	case f.File != "" && f.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+	case f.File != "":
+		return fmt.Sprintf("%s: %s", f.File, f.Message)
+	default:
+		return f.Message
+	}
+}
+
+// 配置来源：只读取其中的ConfigMap和Secret
+type ConfigSource struct {
+	// 目录或文件路径
+	Path string
+	// 优先级，同名对象以优先级高的来源为准
+	Priority int
+}
+
 // 处理选项
 type ProcessOptions struct {
 	// 输入目录
 	InputDir string
 
+	// 流模式读取的文件，"-"表示标准输入；设置后不扫描输入目录，处理后的YAML写入标准输出
+	Files []string
+
+	// 只用于填充配置缓存的额外来源，不会被改写
+	ConfigSources []ConfigSource
+
+	// 是否从集群读取ConfigMap和Secret
+	FromCluster bool
+
+	// kubeconfig路径和上下文
+	Kubeconfig  string
+	KubeContext string
+
+	// 从集群读取的命名空间，为空时使用上下文的命名空间
+	ClusterNamespaces []string
+
+	// 只读取集群中Secret的键，不保留值
+	SecretKeysOnly bool
+
 	// 输出目录
 	OutputDir string
 
 	// 处理模式
 	Mode string
 
+	// 只扫描匹配这些glob模式的文件
+	Include []string
+
+	// 跳过匹配这些glob模式的文件和目录
+	Exclude []string
+
 	// 是否强制覆盖
 	Force bool
 
 	// 是否执行预检查
 	Precheck bool
+
+	// 检查模式：只验证文件是否已处理完毕，不写入任何文件
+	Check bool
+
+	// 以非零退出码结束的条件: change, warning, error
+	FailOn string
+
+	// 名称解析策略，按优先级排列
+	Resolvers []string
+
+	// 前缀规则: 环境变量名前缀 -> 配置对象名称
+	PrefixRules map[string]string
+
+	// 自定义工作负载配置文件
+	WorkloadConfig string
+
+	// 是否在未知资源中自动查找容器列表
+	DiscoverContainers bool
+
+	// 环境变量引用风格: keyRef, envFrom, auto
+	EnvStyle string
+
+	// 是否在pod模板上写入checksum/<名称>注解
+	ChecksumAnnotations bool
+
+	// 为无法解析的环境变量生成占位的ConfigMap/Secret并引用它们
+	CreateMissing bool
+
+	// externalize生成的配置对象命名方式: exact, workload
+	ExternalizeNaming string
+
+	// externalize生成的配置清单文件，为空时使用输入目录下的默认文件
+	ExternalizeFile string
+
+	// 判断环境变量是否为敏感信息的名称模式，为空时使用默认模式；也用于区分占位的ConfigMap和Secret
+	SecretPatterns []string
+
+	// 干运行模式的差异格式: unified, side-by-side, json-patch
+	DiffFormat string
+
+	// 差异的上下文行数
+	DiffContext int
+
+	// 差异是否着色
+	Color bool
+
+	// 文件中有无法解析的文档时的处理策略: keep, skip
+	OnParseError string
+
+	// 并发处理的文件数，0表示使用CPU核数
+	Jobs int
+
+	// 报告格式: text, json, sarif, junit
+	ReportFormat string
+
+	// 报告输出文件，为空时输出到标准输出
+	ReportFile string
 }
 
 // 解析的K8s资源
@@ -68,15 +347,18 @@ type KubeResource struct {
 		Labels    map[string]string `yaml:"labels,omitempty"`
 	} `yaml:"metadata"`
 
-	// 规格(仅适用于工作负载)
-	Spec map[string]interface{} `yaml:"spec,omitempty"`
-
-	// 数据(用于ConfigMap)
+	// 数据(ConfigMap中为明文，Secret中为base64编码)
 	Data map[string]string `yaml:"data,omitempty"`
 
+	// 二进制数据(用于ConfigMap，base64编码)
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+
 	// 字符串数据(用于Secret)
 	StringData map[string]string `yaml:"stringData,omitempty"`
 
 	// Secret类型
 	Type string `yaml:"type,omitempty"`
+
+	// 只读取了键的Secret（--secret-keys-only），值均为空字符串
+	KeysOnly bool `yaml:"-"`
 }